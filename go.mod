@@ -1,16 +1,22 @@
 module github.com/jpillora/media-sort
 
-go 1.13
+go 1.17
 
 require (
 	github.com/agnivade/levenshtein v1.0.3
 	github.com/fatih/color v1.9.0
-	github.com/fsnotify/fsnotify v1.4.7 // indirect
-	github.com/hashicorp/go-multierror v1.1.0 // indirect
 	github.com/jpillora/opts v1.2.0
 	github.com/jpillora/sizestr v1.0.0
+	golang.org/x/sys v0.5.0
+	golang.org/x/text v0.13.0
+	gopkg.in/fsnotify.v1 v1.4.7
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.6 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/posener/complete v1.2.3 // indirect
-	golang.org/x/sys v0.0.0-20200602225109-6fdc65e7d980 // indirect
-	gopkg.in/fsnotify.v1 v1.4.7
 )
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 	"time"
 
 	mediasort "github.com/jpillora/media-sort/sort"
@@ -23,17 +24,95 @@ by default, tv series are moved to:
 and movies are moved to:
   ./<title> (<year>).<ext>
 
-to modify the these paths, you can use the --tv-template and
+to use the folder structure expected by your media server, set
+--layout to one of plex, jellyfin, emby or kodi. for example, the
+plex layout moves tv series and movies to:
+  ./<title> {tvdb-<id>}/Season <season>/<title> - S<season>E<episode>.<ext>
+  ./<title> (<year>) {tmdb-<id>}/<title> (<year>).<ext>
+
+to further modify the these paths, you can use the --tv-template and
 --movie-template options. These options describe the new file path for
 tv series and movies using Go template syntax. You can find the
 default values here:
   https://godoc.org/github.com/jpillora/media-sort/sort#pkg-variables
 and you can view all possible template variables here:
   https://godoc.org/github.com/jpillora/media-sort/sort#Result
+
+along with the standard Go template functions, templates may use:
+  pad <width> <n>          zero pad a number (pad 3 7 is 007)
+  sortTitle <s>            move leading articles ("The Office" is "Office, The")
+  initial <s>              first letter, ignoring articles ("The Matrix" is "M")
+  title, lower, upper <s>  change the case of a string
+  trim <s>                 remove surrounding whitespace
+  replace <old> <new> <s>  replace all occurrences of old with new
+  ascii <s>                transliterate into plain ascii ("Amélie" is "Amelie")
+  slug <s>                 lower-case dash-separated ascii ("Amélie" is "amelie")
+  extraDir <extra>         the folder used for an extra ("trailer" is "Trailers")
+
+every run which changes files is recorded in a journal, to
+revert the latest run (or a specific run) use:
+  media-sort undo [run-id]
+
+to review a sort before any files are changed, use:
+  media-sort plan [options] <target> [target] ...
+  media-sort apply <plan>
+
+hooks (--exec-after and --exec-after-run) run in a shell, and are
+described by these environment variables:
+  MEDIA_SORT_SOURCE, MEDIA_SORT_DESTINATION, MEDIA_SORT_ACTION,
+  MEDIA_SORT_TYPE, MEDIA_SORT_TITLE, MEDIA_SORT_YEAR, MEDIA_SORT_ACCURACY,
+  MEDIA_SORT_SEASON, MEDIA_SORT_EPISODE, MEDIA_SORT_IMDB_ID (each file)
+  MEDIA_SORT_SORTED, MEDIA_SORT_SKIPPED, MEDIA_SORT_FAILED,
+  MEDIA_SORT_DESTINATIONS, MEDIA_SORT_RUN_ID (each run)
+`
+	undo = `
+reverts the files moved, copied and linked by a previous run,
+in reverse order. files which have changed since the run are
+reported as conflicts and left untouched.
+`
+	plan = `
+searches for and resolves what sorting the targets would do, without
+changing any files, and writes these operations to a plan file which
+can be reviewed, and then performed exactly using media-sort apply.
+`
+	apply = `
+performs the operations of a plan written by media-sort plan, in order
+and without searching again. operations whose source has changed, or
+whose destination has since been created or changed, fail and are reported.
 `
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "undo":
+			mainUndo()
+			return
+		case "plan":
+			mainPlan()
+			return
+		case "apply":
+			mainApply()
+			return
+		}
+	}
+	c := defaultConfig()
+	opts.New(&c).
+		Name("media-sort").
+		Repo("github.com/jpillora/media-sort").
+		DocAfter("usage", "info", info).
+		DocBefore("version", "pathtemplates", pathTemplates).
+		SetLineWidth(128).
+		Version(version).
+		Parse()
+
+	if err := mediasort.FileSystemSort(c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//defaultConfig is the default sort configuration
+func defaultConfig() mediasort.Config {
 	c := mediasort.Config{
 		Extensions:        "mp4,m4v,avi,mkv,mpeg,mpg,mov,webm",
 		Concurrency:       6,
@@ -42,18 +121,59 @@ func main() {
 		WatchDelay:        3 * time.Second,
 		AccuracyThreshold: 95, //100 is perfect match,
 		Action:            mediasort.MoveAction,
+		Junk:              mediasort.DefaultJunk,
 	}
+	c.Sanitize = mediasort.PosixSanitize
+	c.MaxNameLength = 255
+	c.SymlinkTarget = mediasort.AbsoluteSymlink
+	c.ReviewThreshold = 100
+	c.ExecTimeout = 10 * time.Minute
+	c.WebhookRetries = 3
+	return c
+}
 
+func mainUndo() {
+	c := mediasort.UndoConfig{}
 	opts.New(&c).
-		Name("media-sort").
+		Name("media-sort undo").
 		Repo("github.com/jpillora/media-sort").
-		DocAfter("usage", "info", info).
-		DocBefore("version", "pathtemplates", pathTemplates).
+		DocAfter("usage", "info", undo).
+		Version(version).
+		ParseArgs(os.Args[1:])
+
+	if err := mediasort.Undo(c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func mainPlan() {
+	c := mediasort.PlanConfig{
+		Config:   defaultConfig(),
+		PlanFile: "media-sort-plan.json",
+	}
+	opts.New(&c).
+		Name("media-sort plan").
+		Repo("github.com/jpillora/media-sort").
+		DocAfter("usage", "info", plan).
 		SetLineWidth(128).
 		Version(version).
-		Parse()
+		ParseArgs(os.Args[1:])
 
-	if err := mediasort.FileSystemSort(c); err != nil {
+	if err := mediasort.MakePlan(c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func mainApply() {
+	c := mediasort.ApplyConfig{}
+	opts.New(&c).
+		Name("media-sort apply").
+		Repo("github.com/jpillora/media-sort").
+		DocAfter("usage", "info", apply).
+		Version(version).
+		ParseArgs(os.Args[1:])
+
+	if err := mediasort.Apply(c); err != nil {
 		log.Fatal(err)
 	}
 }
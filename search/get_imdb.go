@@ -31,15 +31,17 @@ func imdbGet(id imdbID, mediatype MediaType) (Result, error) {
 		return Result{}, fmt.Errorf("movieDB error: %s: %s", id, data.StatusMessage)
 	}
 	//pick first result
+	results := []movieDBResult{}
 	if mediatype == Series || mediatype == "" {
-		for _, series := range data.TVResults {
-			return series.toResult()
-		}
+		results = append(results, data.TVResults...)
 	}
 	if mediatype == Movie || mediatype == "" {
-		for _, movie := range data.MovieResults {
-			return movie.toResult()
-		}
+		results = append(results, data.MovieResults...)
+	}
+	for _, mr := range results {
+		r, err := mr.toResult()
+		r.IMDBID = string(id)
+		return r, err
 	}
 	return Result{}, fmt.Errorf("movieDB error: no match for %s (in %d)", id, len(data.MovieResults)+len(data.TVResults))
 }
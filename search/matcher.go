@@ -1,6 +1,7 @@
 package mediasearch
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -20,20 +21,28 @@ type matcher struct {
 }
 
 func (m *matcher) add(r Result) {
+	//match against both the localized and original titles,
+	//the closest one becomes the title
+	r.Accuracy = accuracy(m.query, r.Title)
+	if r.OriginalTitle != "" {
+		if a := accuracy(m.query, r.OriginalTitle); a > r.Accuracy {
+			r.Accuracy = a
+			r.Title, r.OriginalTitle = r.OriginalTitle, r.Title
+		}
+	}
 	if m.resultMap == nil {
 		m.resultMap = map[string]*Result{}
 	} else if rother, ok := m.resultMap[r.Title]; ok {
 		r.IsDupe = true
 		rother.IsDupe = true
 	}
-	r.Accuracy = accuracy(m.query, r.Title)
 	m.resultMap[r.Title] = &r
 	m.resultSlice = append(m.resultSlice, &r)
 }
 
 func (m *matcher) bestMatch() (Result, error) {
 	if len(m.resultSlice) == 0 {
-		return Result{}, fmt.Errorf("No results")
+		return Result{}, ErrNoResults
 	}
 	sort.Sort(m)
 	if debugMode {
@@ -44,11 +53,25 @@ func (m *matcher) bestMatch() (Result, error) {
 	}
 	r := m.resultSlice[0]
 	if r.Accuracy < m.threshold {
-		return Result{}, fmt.Errorf("No results (closest result was '%s' with an accuracy score of %d)", r.Title, r.Accuracy)
+		return Result{}, &ThresholdError{Closest: *r, Threshold: m.threshold}
 	}
 	return *r, nil
 }
 
+//ErrNoResults is returned when a search finds nothing
+var ErrNoResults = errors.New("No results")
+
+//ThresholdError is returned when the closest
+//result is below the accuracy threshold
+type ThresholdError struct {
+	Closest   Result
+	Threshold int
+}
+
+func (e *ThresholdError) Error() string {
+	return fmt.Sprintf("No results (closest result was '%s' with an accuracy score of %d)", e.Closest.Title, e.Closest.Accuracy)
+}
+
 func (m *matcher) Len() int { return len(m.resultSlice) }
 func (m *matcher) Swap(i, j int) {
 	m.resultSlice[i], m.resultSlice[j] = m.resultSlice[j], m.resultSlice[i]
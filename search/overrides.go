@@ -0,0 +1,248 @@
+package mediasearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//Override forces the result of a search. When the title
+//is empty, the result is fetched using the IMDB ID.
+type Override struct {
+	Title  string    `json:"title,omitempty"`
+	Year   string    `json:"year,omitempty"`
+	Type   MediaType `json:"type,omitempty"`
+	IMDBID string    `json:"imdb_id,omitempty"`
+	TMDBID int       `json:"tmdb_id,omitempty"`
+	TVDBID int       `json:"tvdb_id,omitempty"`
+}
+
+//Overrides maps normalized queries, or directory paths, to overrides
+type Overrides map[string]Override
+
+//overridesLock protects the overrides map and file
+var overridesLock sync.Mutex
+var overrides = Overrides{}
+
+//OverrideOf returns an override forcing the result r
+func OverrideOf(r Result) Override {
+	return Override{
+		Title:  r.Title,
+		Year:   r.Year,
+		Type:   r.Type,
+		IMDBID: r.IMDBID,
+		TMDBID: r.TMDBID,
+		TVDBID: r.TVDBID,
+	}
+}
+
+func (o Override) validate() error {
+	if o.Title == "" && o.IMDBID == "" {
+		return fmt.Errorf("Override requires a title or an imdb_id")
+	}
+	if o.Year != "" && !onlyYear.MatchString(o.Year) {
+		return fmt.Errorf("Invalid year (%s)", o.Year)
+	}
+	if o.Type != "" && o.Type != Movie && o.Type != Series {
+		return fmt.Errorf("Invalid media type (%s)", o.Type)
+	}
+	return nil
+}
+
+//result converts the override into a perfect match
+func (o Override) result(mediatype MediaType) (Result, error) {
+	if o.Type == "" {
+		o.Type = mediatype
+	}
+	//only series have tvdb ids
+	if o.Type == "" && o.TVDBID != 0 {
+		o.Type = Series
+	}
+	r := Result{
+		Title:  o.Title,
+		Year:   o.Year,
+		Type:   o.Type,
+		IMDBID: o.IMDBID,
+		TMDBID: o.TMDBID,
+		TVDBID: o.TVDBID,
+	}
+	if r.Title == "" {
+		fetched, err := imdbResult(o.IMDBID, o.Type)
+		if err != nil {
+			return Result{}, err
+		}
+		r = fetched
+		if o.Year != "" {
+			r.Year = o.Year
+		}
+	} else if r.Type == "" && o.IMDBID != "" {
+		fetched, err := imdbResult(o.IMDBID, "")
+		if err != nil {
+			return Result{}, err
+		}
+		r.Type = fetched.Type
+	}
+	if r.Type == "" {
+		return Result{}, fmt.Errorf("Override of '%s' requires a type (movie or series)", r.Title)
+	}
+	r.Accuracy = 100
+	return r, nil
+}
+
+//imdbResults caches the results of imdb ids, so
+//overrides only fetch them once
+var imdbLock sync.Mutex
+var imdbResults = map[string]Result{}
+
+//imdbResult fetches the result of an imdb id once
+func imdbResult(id string, mediatype MediaType) (Result, error) {
+	imdbLock.Lock()
+	defer imdbLock.Unlock()
+	key := id + "|" + string(mediatype)
+	if r, ok := imdbResults[key]; ok {
+		return r, nil
+	}
+	r, err := imdbGet(imdbID(id), mediatype)
+	if err != nil {
+		return Result{}, err
+	}
+	imdbResults[key] = r
+	return r, nil
+}
+
+//overrideKey returns the key of a query or path. Paths contain a
+//separator, while normalized queries never do. Relative paths are
+//relative to dir.
+func overrideKey(key, dir string) string {
+	if strings.ContainsAny(key, `/\`) {
+		if !filepath.IsAbs(key) {
+			key = filepath.Join(dir, key)
+		}
+		return filepath.Clean(key)
+	}
+	return Normalize(key)
+}
+
+//readOverrides reads the overrides file at path,
+//a missing file has no overrides
+func readOverrides(path string) (Overrides, error) {
+	o := Overrides{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	} else if err != nil {
+		return nil, err
+	}
+	raw := Overrides{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("Invalid overrides file %s: %s", path, err)
+	}
+	dir := filepath.Dir(path)
+	for key, override := range raw {
+		if err := override.validate(); err != nil {
+			return nil, fmt.Errorf("Invalid override '%s': %s", key, err)
+		}
+		o[overrideKey(key, dir)] = override
+	}
+	return o, nil
+}
+
+//LoadOverrides reads the overrides file at path, which is a json
+//object of queries or directory paths to overrides, and uses them
+//in all following searches
+func LoadOverrides(path string) error {
+	o, err := readOverrides(path)
+	if err != nil {
+		return err
+	}
+	overridesLock.Lock()
+	overrides = o
+	overridesLock.Unlock()
+	//cached results may have been overridden
+	lock.Lock()
+	cache = map[string]Result{}
+	lock.Unlock()
+	return nil
+}
+
+//SaveOverride adds an override of query to the overrides
+//file at path, and uses it in all following searches
+func SaveOverride(path, query string, override Override) error {
+	if err := override.validate(); err != nil {
+		return err
+	}
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+	//update the file as written, keeping other overrides
+	raw := Overrides{}
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return fmt.Errorf("Invalid overrides file %s: %s", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	key := overrideKey(query, filepath.Dir(path))
+	for k := range raw {
+		if overrideKey(k, filepath.Dir(path)) == key {
+			delete(raw, k)
+		}
+	}
+	raw[key] = override
+	b, err = json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	overrides[key] = override
+	lock.Lock()
+	delete(cache, query)
+	lock.Unlock()
+	return nil
+}
+
+//queryOverride returns the override of query
+func queryOverride(query string) (Override, bool) {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+	o, ok := overrides[Normalize(query)]
+	return o, ok
+}
+
+//PathOverride returns the overridden result of the file at path,
+//when path or one of its parent directories has an override
+func PathOverride(path, mediatype string) (Result, bool, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Result{}, false, err
+	}
+	overridesLock.Lock()
+	o, ok := Override{}, false
+	for p := path; !ok; p = filepath.Dir(p) {
+		o, ok = overrides[p]
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+	overridesLock.Unlock()
+	if !ok {
+		return Result{}, false, nil
+	}
+	r, err := o.result(MediaType(mediatype))
+	return r, true, err
+}
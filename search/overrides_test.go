@@ -0,0 +1,78 @@
+package mediasearch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "media-sort-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer LoadOverrides("")
+	path := filepath.Join(dir, "overrides.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"The Office": {"title": "The Office", "year": "2005", "type": "series"},
+		"uk/The Office": {"title": "The Office", "year": "2001"}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadOverrides(path); err != nil {
+		t.Fatal(err)
+	}
+	//queries are normalized and never searched
+	r, err := SearchThreshold("the.office", "", "", 100)
+	if err != nil || r.Year != "2005" || r.Accuracy != 100 {
+		t.Fatalf("unexpected result: %+v %v", r, err)
+	}
+	//directory overrides apply to all files within
+	r, ok, err := PathOverride(filepath.Join(dir, "uk", "The Office", "S01E01.mkv"), "series")
+	if err != nil || !ok || r.Year != "2001" || r.Type != Series {
+		t.Fatalf("unexpected result: %+v %v %v", r, ok, err)
+	}
+	if _, ok, _ := PathOverride(filepath.Join(dir, "us", "S01E01.mkv"), ""); ok {
+		t.Fatal("unexpected override")
+	}
+	//saved overrides are used and kept
+	if err := SaveOverride(path, "Dark", Override{Title: "Dark", Year: "2017", TVDBID: 334824}); err != nil {
+		t.Fatal(err)
+	}
+	o, err := readOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o) != 3 || o["dark"].Year != "2017" {
+		t.Fatalf("unexpected overrides: %+v", o)
+	}
+	//untyped overrides are typed by their ids
+	if r, err := Search("dark", "", ""); err != nil || r.Title != "Dark" || r.Type != Series {
+		t.Fatalf("unexpected result: %+v %v", r, err)
+	}
+	if err := SaveOverride(path, "Heat", Override{Title: "Heat"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Search("heat", "", ""); err == nil {
+		t.Fatal("expected untyped override to fail")
+	}
+	if r, err := Search("heat", "", "movie"); err != nil || r.Type != Movie {
+		t.Fatalf("unexpected result: %+v %v", r, err)
+	}
+	//imdb ids are only fetched once
+	imdbLock.Lock()
+	imdbResults["tt0113277|"] = Result{Title: "Heat", Year: "1995", Type: Movie}
+	imdbLock.Unlock()
+	if err := SaveOverride(path, "Heat", Override{IMDBID: "tt0113277"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := Search("heat", "", ""); err != nil || r.Year != "1995" || r.Type != Movie {
+		t.Fatalf("unexpected result: %+v %v", r, err)
+	}
+	if err := SaveOverride(path, "broken", Override{}); err == nil {
+		t.Fatal("expected invalid override")
+	}
+}
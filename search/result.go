@@ -12,11 +12,19 @@ const (
 
 // Result is a single search result
 type Result struct {
-	Title    string
-	Year     string
-	Type     MediaType
-	IsDupe   bool
-	Accuracy int
+	Title string
+	//OriginalTitle is the title in its original language,
+	//set when it differs from the (localized) Title
+	OriginalTitle string
+	Year          string
+	Type          MediaType
+	IsDupe        bool
+	Accuracy      int
+	Plot          string
+	//provider IDs, zero when unknown
+	IMDBID string
+	TMDBID int
+	TVDBID int
 }
 
 func (r Result) String() string {
@@ -3,6 +3,7 @@ package mediasearch
 import (
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 
 	"github.com/fatih/color"
@@ -23,6 +24,10 @@ var lock sync.Mutex
 var cache = map[string]Result{} //TODO(jpillora) global cache will grow forever, should convert to LRU cache
 var inflight = map[string]*sync.WaitGroup{}
 
+//candidate lists are cached by query, year and media type
+var candidateCache = map[string][]Result{}
+var candidateInflight = map[string]*sync.WaitGroup{}
+
 //Search for IMDB data (query is required, year and media type are optional)
 func Search(query, year, mediatype string) (Result, error) {
 	return SearchThreshold(query, year, mediatype, DefaultThreshold)
@@ -30,12 +35,12 @@ func Search(query, year, mediatype string) (Result, error) {
 
 //SearchThreshold for IMDB data with a specific match threshoold
 func SearchThreshold(query, year, mediatype string, threshold int) (Result, error) {
-	if year != "" && !onlyYear.MatchString(year) {
-		return Result{}, fmt.Errorf("Invalid year (%s)", year)
+	if err := validate(year, mediatype); err != nil {
+		return Result{}, err
 	}
-	mt := MediaType(mediatype)
-	if mediatype != "" && mt != Movie && mt != Series {
-		return Result{}, fmt.Errorf("Invalid media type (%s)", mediatype)
+	//overrides are used instead of any search
+	if o, ok := queryOverride(query); ok {
+		return o.result(MediaType(mediatype))
 	}
 	lock.Lock()
 	//cached searches are served instantly
@@ -62,9 +67,94 @@ func SearchThreshold(query, year, mediatype string, threshold int) (Result, erro
 		delete(inflight, query)
 		lock.Unlock()
 	}()
+	m, err := searchAll(query, year, MediaType(mediatype), threshold)
+	if err != nil {
+		return Result{}, err
+	}
+	if r, err = m.bestMatch(); err != nil {
+		return Result{}, err
+	}
+	lock.Lock()
+	cache[query] = r
+	lock.Unlock()
+	return r, nil
+}
+
+//Candidates searches for query, returning all tv/movie results
+//(year and media type are optional) ordered from best to worst match
+func Candidates(query, year, mediatype string) ([]Result, error) {
+	if err := validate(year, mediatype); err != nil {
+		return nil, err
+	}
+	if o, ok := queryOverride(query); ok {
+		r, err := o.result(MediaType(mediatype))
+		if err != nil {
+			return nil, err
+		}
+		return []Result{r}, nil
+	}
+	key := query + "|" + year + "|" + mediatype
+	lock.Lock()
+	//cached searches are served instantly
+	if results, ok := candidateCache[key]; ok {
+		lock.Unlock()
+		return results, nil
+	}
+	//duplicate searchs wait on the first
+	if w, ok := candidateInflight[key]; ok {
+		lock.Unlock()
+		w.Wait()
+		lock.Lock()
+		results, ok := candidateCache[key]
+		lock.Unlock()
+		if ok {
+			return results, nil
+		}
+		//the first search failed, try again
+		return Candidates(query, year, mediatype)
+	}
+	w := &sync.WaitGroup{}
+	w.Add(1)
+	candidateInflight[key] = w
+	lock.Unlock()
+	defer func() {
+		lock.Lock()
+		w.Done()
+		delete(candidateInflight, key)
+		lock.Unlock()
+	}()
+	m, err := searchAll(query, year, MediaType(mediatype), 0)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(m)
+	results := make([]Result, len(m.resultSlice))
+	for i, r := range m.resultSlice {
+		results[i] = *r
+	}
+	lock.Lock()
+	candidateCache[key] = results
+	lock.Unlock()
+	return results, nil
+}
+
+func validate(year, mediatype string) error {
+	if year != "" && !onlyYear.MatchString(year) {
+		return fmt.Errorf("Invalid year (%s)", year)
+	}
+	mt := MediaType(mediatype)
+	if mediatype != "" && mt != Movie && mt != Series {
+		return fmt.Errorf("Invalid media type (%s)", mediatype)
+	}
+	return nil
+}
+
+//searchAll searches each engine until one has results,
+//which are collected by a matcher
+func searchAll(query, year string, mt MediaType, threshold int) (*matcher, error) {
 	//show searches
 	msg := fmt.Sprintf("Searching %s", color.CyanString(query))
-	if m := string(mediatype); m != "" {
+	if m := string(mt); m != "" {
 		msg += " (" + color.CyanString(m) + ")"
 	}
 	if year != "" {
@@ -73,26 +163,31 @@ func SearchThreshold(query, year, mediatype string, threshold int) (Result, erro
 	log.Print(msg)
 	//search various search engines
 	var searcheEngines = movieSearches
-	if MediaType(mediatype) == Series {
+	if mt == Series {
 		searcheEngines = tvSearches
 	}
 	//search returns results
 	var results []Result
-	var err error
+	var failed error
 	for _, s := range searcheEngines {
+		var err error
 		results, err = s(query, year, mt)
 		if len(results) > 0 {
 			break
 		}
+		if err != nil {
+			failed = err
+		}
 	}
-	if len(results) == 0 && err != nil {
-		return Result{}, fmt.Errorf("No results (%s)", err)
+	//a failed search engine may have had results
+	if len(results) == 0 && failed != nil {
+		return nil, fmt.Errorf("Search failed (%s)", failed)
 	}
 	if len(results) == 0 {
-		return Result{}, fmt.Errorf("No results")
+		return nil, ErrNoResults
 	}
 	//matcher picks result (r)
-	m := matcher{query: query, year: year, threshold: threshold}
+	m := &matcher{query: query, year: year, threshold: threshold}
 	otherTypes := []Result{}
 	for _, result := range results {
 		//only consider tv/movies
@@ -100,7 +195,7 @@ func SearchThreshold(query, year, mediatype string, threshold int) (Result, erro
 			continue
 		}
 		//if media type set, ensure match
-		if mediatype != "" && result.Type != mt {
+		if mt != "" && result.Type != mt {
 			otherTypes = append(otherTypes, result)
 		} else {
 			m.add(result)
@@ -112,11 +207,5 @@ func SearchThreshold(query, year, mediatype string, threshold int) (Result, erro
 			m.add(result)
 		}
 	}
-	if r, err = m.bestMatch(); err != nil {
-		return Result{}, err
-	}
-	lock.Lock()
-	cache[query] = r
-	lock.Unlock()
-	return r, nil
+	return m, nil
 }
@@ -50,7 +50,10 @@ func searchGoogle(query, year string, mediatype MediaType) ([]Result, error) {
 	//extract imdb ID
 	m := imdbIDRe.FindStringSubmatch(loc)
 	if len(m) == 0 {
-		return nil, fmt.Errorf("No IMDB match (%s)", loc)
+		if debugMode {
+			log.Printf("No IMDB match (%s)", loc)
+		}
+		return nil, nil
 	}
 	//lookup imdb ID using OMDB
 	r, err := imdbGet(imdbID(m[1]), mediatype)
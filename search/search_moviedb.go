@@ -73,16 +73,17 @@ type movieDBResult struct {
 }
 
 func (mr movieDBResult) toResult() (Result, error) {
-	r := Result{}
-	movieTitle := ""
-	if mr.OriginalTitle != "" {
-		movieTitle = mr.OriginalTitle
-	} else if mr.Title != "" {
-		movieTitle = mr.Title
+	r := Result{TMDBID: mr.ID, Plot: mr.Overview}
+	movieTitle, originalTitle := mr.Title, mr.OriginalTitle
+	if movieTitle == "" {
+		movieTitle, originalTitle = originalTitle, ""
 	}
 	if movieTitle != "" && mr.ReleaseDate != "" {
 		r.Type = Movie
 		r.Title = movieTitle
+		if originalTitle != movieTitle {
+			r.OriginalTitle = originalTitle
+		}
 		m := getYear.FindStringSubmatch(mr.ReleaseDate)
 		if len(m) == 0 {
 			return r, fmt.Errorf("movieDB error: No movie year: %s", mr.ReleaseDate)
@@ -92,6 +93,9 @@ func (mr movieDBResult) toResult() (Result, error) {
 	} else if mr.Name != "" && mr.FirstAirDate != "" {
 		r.Type = Series
 		r.Title = mr.Name
+		if mr.OriginalName != mr.Name {
+			r.OriginalTitle = mr.OriginalName
+		}
 		m := getYear.FindStringSubmatch(mr.FirstAirDate)
 		if len(m) == 0 {
 			return Result{}, fmt.Errorf("movieDB error: No series year: %s", mr.FirstAirDate)
@@ -0,0 +1,31 @@
+package mediasearch
+
+import "testing"
+
+func TestCandidatesCache(t *testing.T) {
+	searched := 0
+	defer func(s []search) { movieSearches = s }(movieSearches)
+	movieSearches = []search{func(query, year string, mt MediaType) ([]Result, error) {
+		searched++
+		return []Result{{Title: "Heat", Year: "1995", Type: Movie}}, nil
+	}}
+	for i := 0; i < 3; i++ {
+		results, err := Candidates("heat", "1995", "movie")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Title != "Heat" {
+			t.Fatalf("unexpected results: %v", results)
+		}
+	}
+	if searched != 1 {
+		t.Fatalf("expected one search, got %d", searched)
+	}
+	//other years are searched separately
+	if _, err := Candidates("heat", "1986", "movie"); err != nil {
+		t.Fatal(err)
+	}
+	if searched != 2 {
+		t.Fatalf("expected two searches, got %d", searched)
+	}
+}
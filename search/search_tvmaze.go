@@ -39,6 +39,9 @@ func searchTVMaze(query, year string, mediatype MediaType) ([]Result, error) {
 			Year:     m[1],
 			Type:     Series,
 			Accuracy: accuracy(query, tvMazeResult.Show.Name),
+			IMDBID:   tvMazeResult.Show.Externals.Imdb,
+			Plot:     stripHTML(tvMazeResult.Show.Summary),
+			TVDBID:   tvMazeResult.Show.Externals.Thetvdb,
 		})
 	}
 	return rs, nil
@@ -56,8 +59,9 @@ type tvMazeResult struct {
 			} `json:"self"`
 		} `json:"_links"`
 		Externals struct {
-			Thetvdb int `json:"thetvdb"`
-			Tvrage  int `json:"tvrage"`
+			Thetvdb int    `json:"thetvdb"`
+			Tvrage  int    `json:"tvrage"`
+			Imdb    string `json:"imdb"`
 		} `json:"externals"`
 		Genres []string `json:"genres"`
 		ID     int      `json:"id"`
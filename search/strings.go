@@ -1,6 +1,7 @@
 package mediasearch
 
 import (
+	"html"
 	"regexp"
 	"strings"
 
@@ -8,7 +9,7 @@ import (
 )
 
 var (
-	nonalpha        = regexp.MustCompile(`[^a-z0-9]`)
+	nonalpha        = regexp.MustCompile(`[^\p{L}\p{M}\p{N}]`)
 	yearstr         = `(19\d\d|20\d\d)`
 	onlyYear        = regexp.MustCompile(`^` + yearstr + `$`)
 	getYear         = regexp.MustCompile(`\b` + yearstr + `\b`)
@@ -21,10 +22,12 @@ var (
 	year            = regexp.MustCompile(`^(.+?\b)` + yearstr + `\b`)
 	joinedepiseason = regexp.MustCompile(`^(.+?\b)(\d)(\d{2})\b`)
 	partnum         = regexp.MustCompile(`^(.+?\b)(\d{1,2})\b`)
+	htmlTags        = regexp.MustCompile(`<[^>]*>`)
 )
 
 // Normalize strings to become search terms
 func Normalize(s string) string {
+	s = Fold(s)
 	s = strings.ToLower(s)
 	s = nonalpha.ReplaceAllString(s, " ")
 	s = encodings.ReplaceAllString(s, "")
@@ -54,3 +57,8 @@ func isNear(a, b string) bool {
 	lendiff := abs(len(a) - len(b))
 	return dist(a, b)-lendiff <= 5
 }
+
+//stripHTML converts an html snippet into plain text
+func stripHTML(s string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTags.ReplaceAllString(s, "")))
+}
@@ -0,0 +1,46 @@
+package mediasearch
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		Input, Expect string
+	}{
+		{"The.Matrix.1999.1080p.BluRay", "the matrix 1999"},
+		{"Amélie", "amelie"},
+		{"Léon: The Professional", "leon the professional"},
+		{"Amélie", "amelie"}, //decomposed (macOS)
+		{"Straße", "strasse"},
+		{"Москва слезам не верит", "moskva slezam ne verit"},
+		{"Ζορμπάς", "zormpas"},
+		{"기생충", "gisaengchung"},
+		{"となりのトトロ", "tonarinototoro"},
+		{"ちょっと", "chotto"},
+		{"千と千尋の神隠し", "千と千尋の神隠し"},
+	} {
+		if got := Normalize(tc.Input); got != tc.Expect {
+			t.Errorf("Normalize(%q) = %q, expected %q", tc.Input, got, tc.Expect)
+		}
+	}
+}
+
+func TestFoldPreservesCase(t *testing.T) {
+	if got := Fold("Москва"); got != "Moskva" {
+		t.Errorf("got %q", got)
+	}
+	if got := Fold("Ærø"); got != "Aero" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMatcherOriginalTitle(t *testing.T) {
+	m := matcher{query: "amelie", threshold: DefaultThreshold}
+	m.add(Result{Title: "Le Fabuleux Destin d'Amélie Poulain", OriginalTitle: "Amélie", Year: "2001", Type: Movie})
+	r, err := m.bestMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Title != "Amélie" || r.Accuracy != 100 {
+		t.Fatalf("unexpected match %+v", r)
+	}
+}
@@ -0,0 +1,193 @@
+package mediasearch
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//Fold converts the provided string into its closest latin
+//representation. Accents are removed (NFKD), latin ligatures are
+//expanded and Cyrillic, Greek, Hangul and Kana (when not mixed
+//with Kanji) are transliterated. Scripts which cannot be
+//transliterated offline (Han, Arabic, Thai, etc) are left as-is.
+//Case is preserved.
+func Fold(s string) string {
+	//kana is only transliterated when there is no kanji
+	//to go with it, mixed text is better searched as-is
+	if !hasHan(s) {
+		s = kanaToRomaji(s)
+	}
+	sb := strings.Builder{}
+	//marks are only dropped when they
+	//follow a (transliterated) latin letter
+	latin := false
+	for _, r := range s {
+		if r >= 0xAC00 && r <= 0xD7A3 {
+			sb.WriteString(hangulToRoman(r))
+			latin = true
+			continue
+		}
+		if t, ok := translit(r); ok {
+			sb.WriteString(t)
+			latin = true
+			continue
+		}
+		for _, d := range norm.NFKD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				if !latin {
+					sb.WriteRune(d)
+				}
+				continue
+			}
+			if t, ok := translit(d); ok {
+				sb.WriteString(t)
+				latin = true
+				continue
+			}
+			sb.WriteRune(d)
+			latin = d < unicode.MaxASCII
+		}
+	}
+	return sb.String()
+}
+
+//translit looks up r in the transliteration
+//table, preserving its case
+func translit(r rune) (string, bool) {
+	l := unicode.ToLower(r)
+	t, ok := translitTable[l]
+	if !ok {
+		return "", false
+	}
+	if l != r && t != "" {
+		//upper case, capitalise the first letter
+		t = strings.ToUpper(t[:1]) + t[1:]
+	}
+	return t, true
+}
+
+func hasHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+//translitTable maps lower case runes into latin
+var translitTable = map[rune]string{
+	//latin letters without a decomposition
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ŋ': "ng", 'ħ': "h", 'ŧ': "t",
+	//cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e",
+	'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye",
+	'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj",
+	'ћ': "c", 'џ': "dz", 'ѓ': "gj", 'ќ': "kj", 'ѕ': "dz",
+	//greek (accents are removed by decomposition)
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z",
+	'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+	'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+//hangul syllables are composed of an initial, a vowel and
+//an optional final, romanized using the Revised Romanization
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp",
+		"s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulVowels = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o",
+		"wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k",
+		"m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t",
+		"k", "t", "p", "t"}
+)
+
+func hangulToRoman(r rune) string {
+	i := int(r - 0xAC00)
+	return hangulInitials[i/588] + hangulVowels[(i%588)/28] + hangulFinals[i%28]
+}
+
+//kana, katakana is shifted into the hiragana block before lookup
+var kanaTable = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+//small ya/yu/yo combine with the preceding "i" kana
+var kanaSmallY = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+func kanaToRomaji(s string) string {
+	rs := []rune(s)
+	for i, r := range rs {
+		//katakana -> hiragana
+		if r >= 0x30A1 && r <= 0x30F6 {
+			rs[i] = r - 0x60
+		}
+	}
+	sb := strings.Builder{}
+	double := false
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		t, ok := kanaTable[r]
+		if !ok {
+			switch r {
+			case 'っ':
+				double = true
+			case 'ー', '・':
+				//long vowels and separators are dropped
+			default:
+				if v, ok := kanaSmallY[r]; ok {
+					sb.WriteString("y" + v)
+				} else {
+					sb.WriteRune(r)
+				}
+			}
+			continue
+		}
+		//combine "ki"+"ya" into "kya", "shi"+"ya" into "sha"
+		if i+1 < len(rs) && strings.HasSuffix(t, "i") && len(t) > 1 {
+			if v, ok := kanaSmallY[rs[i+1]]; ok {
+				t = strings.TrimSuffix(t, "i")
+				if t != "sh" && t != "ch" && t != "j" {
+					t += "y"
+				}
+				t += v
+				i++
+			}
+		}
+		//small tsu doubles the next consonant
+		if double {
+			if strings.HasPrefix(t, "ch") {
+				t = "t" + t
+			} else if t[0] != 'a' && t[0] != 'i' && t[0] != 'u' && t[0] != 'e' && t[0] != 'o' {
+				t = t[:1] + t
+			}
+			double = false
+		}
+		sb.WriteString(t)
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+//DefaultJunk is the default list of file patterns which
+//may be removed along with an emptied source directory
+const DefaultJunk = "*.nfo,*.txt,*.jpg,*.jpeg,*.png,*.sfv,*.srr,*.nzb,*.url,*.md5,*.exe,*.lnk,*sample*,.DS_Store,Thumbs.db,desktop.ini"
+
+//isJunk returns whether the file name matches a junk pattern
+func (fs *fsSort) isJunk(name string) bool {
+	junk := fs.Junk
+	if junk == "" {
+		junk = DefaultJunk
+	}
+	name = strings.ToLower(name)
+	for _, pattern := range strings.Split(junk, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if ok, _ := filepath.Match(pattern, name); ok && pattern != "" {
+			return true
+		}
+	}
+	return false
+}
+
+//onlyJunk returns whether dir (recursively) contains nothing
+//but junk files and directories, and never unsorted media
+func (fs *fsSort) onlyJunk(dir string) (bool, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			if ok, err := fs.onlyJunk(filepath.Join(dir, info.Name())); !ok || err != nil {
+				return false, err
+			}
+			continue
+		}
+		//media is never junk, unless it's a sample
+		media := fs.validExts[filepath.Ext(info.Name())] && !sample.MatchString(strings.ToLower(info.Name()))
+		if media || !info.Mode().IsRegular() || !fs.isJunk(info.Name()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//cleanup removes the directories which media was moved out of, when
+//they're left with only junk. Removal continues up through each parent
+//directory, stopping at the target directory. Removals aren't journaled,
+//so cleanup is only available when the journal is disabled.
+func (fs *fsSort) cleanup() {
+	targets := []string{}
+	for _, t := range fs.Targets {
+		if info, err := os.Stat(t); err == nil && info.IsDir() {
+			targets = append(targets, abs(t))
+		}
+	}
+	//inTarget reports whether dir is strictly inside a target
+	inTarget := func(dir string) bool {
+		for _, t := range targets {
+			if strings.HasPrefix(dir, t+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	dirs := []string{}
+	for dir := range fs.sources {
+		dirs = append(dirs, abs(dir))
+	}
+	//deepest first
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		remove := ""
+		for d := dir; inTarget(d); d = filepath.Dir(d) {
+			ok, err := fs.onlyJunk(d)
+			if err != nil || !ok {
+				break
+			}
+			remove = d
+		}
+		if remove == "" {
+			continue
+		}
+		if err := os.RemoveAll(remove); err != nil {
+			log.Printf("Cleanup %s\n  └─> %s", color.RedString(remove), err)
+			continue
+		}
+		log.Printf("Cleanup %s", color.YellowString(remove))
+	}
+}
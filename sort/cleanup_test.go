@@ -0,0 +1,47 @@
+package mediasort
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir,
+		//sorted release, only junk remains
+		"downloads/Movie.2010/Movie.2010.nfo",
+		"downloads/Movie.2010/Movie.2010-sample.mkv",
+		"downloads/Movie.2010/Subs/.keep.txt",
+		//season pack with an unsorted episode
+		"downloads/Show.S01/Show.S01E02.mkv",
+		"downloads/Show.S01/info.txt",
+		//unknown files are never junk
+		"downloads/Other.2010/notes.doc",
+	)
+	os.MkdirAll(filepath.Join(dir, "downloads/Movie.2010/Subs/English"), 0755)
+	fs := testSort()
+	fs.Targets = []string{filepath.Join(dir, "downloads")}
+	fs.sources = map[string]bool{
+		filepath.Join(dir, "downloads/Movie.2010"): true,
+		filepath.Join(dir, "downloads/Show.S01"):   true,
+		filepath.Join(dir, "downloads/Other.2010"): true,
+	}
+	fs.cleanup()
+	for path, exists := range map[string]bool{
+		"downloads":            true,
+		"downloads/Movie.2010": false,
+		"downloads/Show.S01":   true,
+		"downloads/Other.2010": true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); (err == nil) != exists {
+			t.Fatalf("expected %s exists to be %v", path, exists)
+		}
+	}
+	//removals can't be undone, so cleanup needs the journal disabled
+	c := Config{Cleanup: true, JournalDir: filepath.Join(dir, "journal")}
+	if _, err := newFSSort(c); err == nil {
+		t.Fatal("expected cleanup to require no-journal")
+	}
+}
@@ -0,0 +1,231 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//companion is a file which belongs to a video file,
+//such as subtitles, .nfo files and artwork
+type companion struct {
+	path string
+	//subtitle is true for subtitle files, otherwise
+	//the companion is a sidecar file
+	subtitle bool
+	//suffix is appended to the new base name of the video,
+	//for example ".en.forced.srt" or "-poster.jpg"
+	suffix string
+	//folder level companions (poster.jpg) keep their name
+	folder bool
+}
+
+var (
+	subtitleExts = map[string]bool{".srt": true, ".ass": true, ".ssa": true, ".sub": true,
+		".idx": true, ".vtt": true, ".smi": true, ".sup": true}
+	sidecarExts = map[string]bool{".nfo": true, ".jpg": true, ".jpeg": true, ".png": true, ".tbn": true}
+	//artwork names used by Plex, Jellyfin, Emby and Kodi
+	artNames  = `(poster|folder|cover|fanart|backdrop|background|banner|clearart|clearlogo|disc|discart|landscape|logo|thumb)`
+	folderArt = regexp.MustCompile(`(?i)^` + artNames + `\.(jpe?g|png)$`)
+	//<base>-poster.jpg
+	videoArt    = regexp.MustCompile(`(?i)^-` + artNames + `$`)
+	subsDirs    = regexp.MustCompile(`(?i)^(subs|subtitles)$`)
+	tagSplit    = regexp.MustCompile(`[\.\_\-\ ]+`)
+	subtitleTag = map[string]string{"forced": "forced", "sdh": "sdh", "cc": "cc"}
+)
+
+//findCompanions returns all companion files belonging to the video at
+//path. Sibling files sharing the video's base name are always included.
+//When the video is alone in its directory (a release folder), untagged
+//subtitles, artwork and the Subs/ folder are also included.
+func (fs *fsSort) findCompanions(path string, movie bool) ([]*companion, error) {
+	dir, name := filepath.Split(path)
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	//count the videos in this directory
+	videos := 0
+	for _, info := range infos {
+		if info.Mode().IsRegular() && fs.validExts[filepath.Ext(info.Name())] && !sample.MatchString(strings.ToLower(info.Name())) {
+			videos++
+		}
+	}
+	alone := videos == 1
+	companions := []*companion{}
+	for _, info := range infos {
+		p := filepath.Join(dir, info.Name())
+		if p == filepath.Join(dir, name) {
+			continue
+		}
+		//subtitle folders, either Subs/*.srt or Subs/<base>/*.srt
+		if info.IsDir() && subsDirs.MatchString(info.Name()) {
+			subs, err := ioutil.ReadDir(p)
+			if err != nil {
+				return nil, err
+			}
+			for _, sub := range subs {
+				sp := filepath.Join(p, sub.Name())
+				if sub.IsDir() && sub.Name() == base {
+					inner, err := ioutil.ReadDir(sp)
+					if err != nil {
+						return nil, err
+					}
+					for _, i := range inner {
+						if c := fs.newCompanion(filepath.Join(sp, i.Name()), i, "", true); c != nil && c.subtitle {
+							companions = append(companions, c)
+						}
+					}
+				} else if c := fs.newCompanion(sp, sub, base, alone); c != nil && c.subtitle {
+					companions = append(companions, c)
+				}
+			}
+			continue
+		}
+		c := fs.newCompanion(p, info, base, alone)
+		if c == nil || (c.folder && !movie) {
+			//only movies own their folder's artwork
+			continue
+		}
+		companions = append(companions, c)
+	}
+	return companions, nil
+}
+
+//newCompanion returns the companion for the file at path,
+//or nil when it does not belong to base
+func (fs *fsSort) newCompanion(path string, info os.FileInfo, base string, alone bool) *companion {
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	name := info.Name()
+	ext := strings.ToLower(filepath.Ext(name))
+	subtitle := subtitleExts[ext]
+	if subtitle && fs.SkipSubs || !subtitle && (fs.SkipSidecars || !sidecarExts[ext]) {
+		return nil
+	}
+	//generated nfo files replace existing ones
+	if fs.NFO && ext == ".nfo" {
+		return nil
+	}
+	c := &companion{path: path, subtitle: subtitle}
+	//<base>.en.forced.srt or <base>-poster.jpg
+	if base != "" && strings.HasPrefix(name, base) && len(name) > len(base) &&
+		strings.ContainsAny(name[len(base):len(base)+1], ".-") {
+		rest := name[len(base) : len(name)-len(ext)]
+		//only tags may follow the base name, Movie.Part2.en.srt
+		//and Movie-trailer.nfo belong to other videos
+		switch {
+		case subtitle && subtitleTags(rest):
+			c.suffix = subtitleSuffix(path, rest, ext)
+		case !subtitle && (rest == "" || videoArt.MatchString(rest)):
+			c.suffix = rest + ext
+		default:
+			return nil
+		}
+		return c
+	}
+	//files which don't share the base name, only
+	//belong to a video when it's alone
+	if !alone {
+		return nil
+	}
+	if subtitle {
+		c.suffix = subtitleSuffix(path, strings.TrimSuffix(name, filepath.Ext(name)), ext)
+		return c
+	}
+	if folderArt.MatchString(name) {
+		c.folder = true
+		c.suffix = name
+		return c
+	}
+	if ext == ".nfo" {
+		c.suffix = ext
+		return c
+	}
+	return nil
+}
+
+//subtitleTags returns whether name only contains
+//language, forced/sdh tags and track numbers
+func subtitleTags(name string) bool {
+	for _, t := range tagSplit.Split(strings.ToLower(name), -1) {
+		if _, ok := subtitleTag[t]; ok || t == "" || languageCode(t) != "" {
+			continue
+		}
+		if _, err := strconv.Atoi(t); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//subtitleSuffix extracts the trailing language and forced/sdh tags
+//from the given name, returning a Plex compatible suffix (".en.forced.srt").
+//When the name has no language, it's detected from the file at path.
+func subtitleSuffix(path, name, ext string) string {
+	lang := ""
+	flags := []string{}
+	tokens := tagSplit.Split(strings.ToLower(name), -1)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		t := tokens[i]
+		if f, ok := subtitleTag[t]; ok {
+			flags = append([]string{f}, flags...)
+		} else if t == "hi" && i > 0 && languageCode(tokens[i-1]) != "" {
+			//hearing impaired after a language, otherwise hindi
+			flags = append([]string{"sdh"}, flags...)
+		} else if l := languageCode(t); l != "" && lang == "" {
+			lang = l
+		} else if _, err := strconv.Atoi(t); err != nil && t != "" {
+			break //tags end, title begins
+		}
+	}
+	//untagged, try reading the subtitles
+	if lang == "" {
+		lang = detectLanguage(path)
+	}
+	suffix := ""
+	if lang != "" {
+		suffix += "." + lang
+	}
+	for _, f := range flags {
+		suffix += "." + f
+	}
+	return suffix + ext
+}
+
+//newPath returns the new path for the companion,
+//given the new path of its video
+func (c *companion) newPath(videoPath string) string {
+	if c.folder {
+		return filepath.Join(filepath.Dir(videoPath), c.suffix)
+	}
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + c.suffix
+}
+
+//companionPaths returns the new paths of the companions given the
+//new path of their video, with clashing names made unique (.en.2.srt)
+func companionPaths(companions []*companion, videoPath string) []string {
+	paths := make([]string, len(companions))
+	used := map[string]bool{}
+	for i, c := range companions {
+		p := c.newPath(videoPath)
+		if used[p] && c.subtitle {
+			ext := filepath.Ext(p)
+			stem := strings.TrimSuffix(p, ext)
+			for n := 2; used[p]; n++ {
+				p = stem + "." + strconv.Itoa(n) + ext
+			}
+		}
+		used[p] = true
+		paths[i] = p
+	}
+	return paths
+}
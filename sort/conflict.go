@@ -0,0 +1,223 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+//Conflict is the strategy used when a different
+//file already exists at the destination
+type Conflict string
+
+const (
+	//FailConflict reports an error (the default)
+	FailConflict Conflict = "fail"
+	//SkipConflict leaves the file where it is
+	SkipConflict Conflict = "skip"
+	//RenameConflict adds a numeric suffix ("Movie (2010) (2).mkv")
+	RenameConflict Conflict = "rename"
+	//VersionConflict keeps both as versions ("Movie (2010) - 2160p.mkv")
+	VersionConflict Conflict = "version"
+	//QuarantineConflict moves the losing file into the quarantine directory
+	QuarantineConflict Conflict = "quarantine"
+)
+
+const manifestFile = "manifest.jsonl"
+
+func (c Conflict) validate(quarantineDir string) error {
+	switch c {
+	case "", FailConflict, SkipConflict, RenameConflict, VersionConflict:
+		return nil
+	case QuarantineConflict:
+		if quarantineDir == "" {
+			return errors.New("Quarantine directory is required to quarantine conflicts")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown conflict strategy: %s", c)
+}
+
+//QuarantineEntry is a line of the quarantine manifest
+type QuarantineEntry struct {
+	Time time.Time `json:"time"`
+	//Path of the file in quarantine
+	Path string `json:"path"`
+	//Original path of the file
+	Original string `json:"original"`
+	//Conflict is the file it lost to
+	Conflict string `json:"conflict"`
+	Reason   string `json:"reason"`
+}
+
+//resolution is the outcome of a conflict
+type resolution struct {
+	path        string
+	overwrote   os.FileInfo
+	skip        bool
+	quarantined bool
+	//winner is the existing file a quarantined file lost to
+	winner string
+	reason string
+}
+
+//resolveConflict decides what happens to the file, given the different
+//existing file at newPath. The overwrite options decide whether the
+//existing file is replaced, otherwise the conflict strategy is used.
+func (fs *fsSort) resolveConflict(file *fileSort, newPath string, existing os.FileInfo) (resolution, error) {
+	r := resolution{path: newPath, reason: "file already exists"}
+	prefix := fmt.Sprintf("[#%d/%d]", file.id, len(fs.sorts))
+	replace := fs.Overwrite || (fs.OverwriteIfLarger && file.info.Size() > existing.Size())
+	if !replace && fs.OverwriteIfBetter {
+		decision, quality, reason := fs.compareQuality(file.path, newPath)
+		r.reason = reason
+		switch decision {
+		case replaceDuplicate:
+			log.Printf("%s Replacing duplicate, %s", prefix, reason)
+			replace = true
+		case keepBoth:
+			log.Printf("%s Keeping both duplicates, %s", prefix, reason)
+			r.path = uniquePath(versionPath(newPath, quality.String()))
+			return r, nil
+		case skipDuplicate:
+			if fs.OnConflict != QuarantineConflict {
+				log.Printf("%s Skipped duplicate, %s", prefix, reason)
+				r.skip = true
+				return r, nil
+			}
+		}
+	}
+	if replace {
+		if fs.OnConflict == QuarantineConflict {
+			//keep the replaced file in quarantine
+			q, err := fs.quarantine(newPath, file.path, r.reason)
+			if err != nil {
+				return r, fmt.Errorf("Failed to quarantine '%s': %s", newPath, err)
+			}
+			log.Printf("%s Quarantined existing file %s", prefix, q)
+			return r, nil
+		}
+		r.overwrote = existing
+		return r, nil
+	}
+	switch fs.OnConflict {
+	case SkipConflict:
+		log.Printf("%s Skipped, %s", prefix, r.reason)
+		r.skip = true
+	case RenameConflict:
+		r.path = uniquePath(newPath)
+	case VersionConflict:
+		label := fs.quality(file.path).String()
+		if label == "" {
+			label = "Version"
+		}
+		r.path = uniquePath(versionPath(newPath, label))
+	case QuarantineConflict:
+		//sort the file into quarantine instead
+		r.path = uniquePath(filepath.Join(fs.QuarantineDir, filepath.Base(file.path)))
+		r.quarantined = true
+		r.winner = newPath
+	default:
+		return r, fmt.Errorf("File already exists '%s' (try setting --overwrite or --on-conflict)", newPath)
+	}
+	if r.path != newPath {
+		log.Printf("%s %s\n  └─> %s", prefix, r.reason, color.YellowString(r.path))
+	}
+	return r, nil
+}
+
+//quarantine moves the file at path into the quarantine
+//directory, returning its new path
+func (fs *fsSort) quarantine(path, winner, reason string) (string, error) {
+	fs.quarantineLock.Lock()
+	defer fs.quarantineLock.Unlock()
+	dirs, err := mkdirAll(fs.QuarantineDir)
+	if err != nil {
+		return "", err
+	}
+	q := uniquePath(filepath.Join(fs.QuarantineDir, filepath.Base(path)))
+	if err := move(path, q, fs.Verify); err != nil {
+		return "", err
+	}
+	fs.record(fs.newOperation(step{action: MoveAction}, path, q, nil, dirs))
+	return q, fs.writeManifest(q, path, winner, reason)
+}
+
+//writeManifest appends an entry to the quarantine manifest
+func (fs *fsSort) writeManifest(path, original, winner, reason string) error {
+	b, err := json.Marshal(QuarantineEntry{
+		Time:     time.Now(),
+		Path:     abs(path),
+		Original: abs(original),
+		Conflict: abs(winner),
+		Reason:   reason,
+	})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(fs.QuarantineDir, manifestFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//pathLock serializes the files sorted into a path
+type pathLock struct {
+	sync.Mutex
+	users int
+}
+
+//lockPath waits until no other file is being sorted
+//into path, and returns the func which releases it
+func (fs *fsSort) lockPath(path string) func() {
+	fs.pathsLock.Lock()
+	if fs.paths == nil {
+		fs.paths = map[string]*pathLock{}
+	}
+	l, ok := fs.paths[path]
+	if !ok {
+		l = &pathLock{}
+		fs.paths[path] = l
+	}
+	l.users++
+	fs.pathsLock.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		fs.pathsLock.Lock()
+		if l.users--; l.users == 0 {
+			delete(fs.paths, path)
+		}
+		fs.pathsLock.Unlock()
+	}
+}
+
+//uniquePath returns path, or when it exists, the first
+//free path with a numeric suffix ("Movie (2010) (2).mkv")
+func uniquePath(path string) string {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		p := stem + " (" + strconv.Itoa(n) + ")" + ext
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p
+		}
+	}
+}
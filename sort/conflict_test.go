@@ -0,0 +1,143 @@
+package mediasort
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+func TestResolveConflict(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Movie.2010.2160p.WEB-DL.x265.mkv", "out/Movie (2010).mkv", "out/Movie (2010) (2).mkv")
+	src := filepath.Join(dir, "in", "Movie.2010.2160p.WEB-DL.x265.mkv")
+	dst := filepath.Join(dir, "out", "Movie (2010).mkv")
+	info, _ := os.Stat(src)
+	existing, _ := os.Stat(dst)
+	file := &fileSort{id: 1, path: src, info: info}
+	for _, tc := range []struct {
+		Conflict Conflict
+		Expect   string
+	}{
+		{SkipConflict, ""},
+		{RenameConflict, "Movie (2010) (3).mkv"},
+		{VersionConflict, "Movie (2010) - 2160p WEB-DL x265.mkv"},
+		{QuarantineConflict, filepath.Join("..", "quarantine", "Movie.2010.2160p.WEB-DL.x265.mkv")},
+	} {
+		fs := testSort()
+		fs.OnConflict = tc.Conflict
+		fs.QuarantineDir = filepath.Join(dir, "quarantine")
+		r, err := fs.resolveConflict(file, dst, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.skip != (tc.Expect == "") {
+			t.Fatalf("%s: unexpected skip", tc.Conflict)
+		}
+		if tc.Expect != "" && r.path != filepath.Join(dir, "out", tc.Expect) {
+			t.Fatalf("%s: expected %s, got %s", tc.Conflict, tc.Expect, r.path)
+		}
+	}
+	//fails by default
+	if _, err := testSort().resolveConflict(file, dst, existing); err == nil {
+		t.Fatal("expected conflict error")
+	}
+	//replaced files are quarantined with a manifest
+	fs := testSort()
+	fs.Overwrite = true
+	fs.OnConflict = QuarantineConflict
+	fs.QuarantineDir = filepath.Join(dir, "quarantine")
+	if _, err := fs.resolveConflict(file, dst, existing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "quarantine", "Movie (2010).mkv")); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, "quarantine", manifestFile))
+	if err != nil || !strings.Contains(string(manifest), `"reason":"file already exists"`) {
+		t.Fatalf("unexpected manifest: %s %v", manifest, err)
+	}
+}
+
+func TestConcurrentConflicts(t *testing.T) {
+	defer func(s func(string, string, string, int) (mediasearch.Result, error)) { searchThreshold = s }(searchThreshold)
+	searchThreshold = func(query, year, mediatype string, threshold int) (mediasearch.Result, error) {
+		return mediasearch.Result{Title: "Heat", Year: "1995", Type: mediasearch.Movie, Accuracy: 100}, nil
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	//every copy resolves to the same destination
+	const copies = 8
+	for i := 0; i < copies; i++ {
+		path := filepath.Join(dir, "in", fmt.Sprintf("Heat.1995.part%d.mkv", i))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("copy %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := Config{}
+	c.Targets = []string{filepath.Join(dir, "in")}
+	c.TVDir = filepath.Join(dir, "tv")
+	c.MovieDir = filepath.Join(dir, "movies")
+	c.Extensions = "mkv"
+	c.Concurrency = copies
+	c.FileLimit = 100
+	c.Recursive = true
+	c.Action = MoveAction
+	c.NoJournal = true
+	c.OnConflict = RenameConflict
+	if err := FileSystemSort(c); err != nil {
+		t.Fatal(err)
+	}
+	//no copy replaced another
+	seen := map[string]bool{}
+	infos, err := ioutil.ReadDir(c.MovieDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		b, err := ioutil.ReadFile(filepath.Join(c.MovieDir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[string(b)] = true
+	}
+	if len(infos) != copies || len(seen) != copies {
+		t.Fatalf("expected %d distinct movies, got %d files", copies, len(infos))
+	}
+	//a file waits for others sorting into its destination
+	if err := ioutil.WriteFile(filepath.Join(dir, "in", "Heat.1995.mkv"), []byte("waited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := newFSSort(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(c.MovieDir, "Heat (1995).mkv")
+	os.Remove(dst)
+	release := fs.lockPath(dst)
+	done := make(chan error)
+	go func() { done <- fs.run() }()
+	time.Sleep(100 * time.Millisecond)
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("destination was taken while locked: %v", err)
+	}
+	f.Close()
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(dst); len(b) != 0 {
+		t.Fatalf("destination was replaced: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(c.MovieDir, fmt.Sprintf("Heat (1995) (%d).mkv", copies+1))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,213 @@
+package mediasort
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+//DupePolicy is what happens to files whose content
+//has already been sorted, or is found twice in a scan
+type DupePolicy string
+
+const (
+	//SkipDupes leaves duplicates where they are
+	SkipDupes DupePolicy = "skip"
+	//DeleteDupes removes duplicates
+	DeleteDupes DupePolicy = "delete"
+	//LinkDupes replaces duplicates with a hardlink to the
+	//original, which keeps the path (eg. for seeding) but
+	//not the disk space
+	LinkDupes DupePolicy = "link"
+)
+
+//partialHashSize is the number of bytes hashed at
+//the start and the end of a file
+const partialHashSize = 64 * 1024
+
+func (p DupePolicy) validate() error {
+	switch p {
+	case "", SkipDupes, DeleteDupes, LinkDupes:
+		return nil
+	}
+	return fmt.Errorf("Unknown dupes policy: %s", p)
+}
+
+//partialHash quickly fingerprints the file at path,
+//using its size and the bytes at its start and end
+func partialHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	binary.Write(hash, binary.BigEndian, info.Size())
+	if _, err := io.CopyN(hash, f, partialHashSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if info.Size() > 2*partialHashSize {
+		if _, err := f.Seek(-partialHashSize, io.SeekEnd); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(hash, f); err != nil {
+			return nil, err
+		}
+	} else if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+//sameContent returns whether the files at a and b have the
+//same checksum, comparing their partial hashes first
+func sameContent(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if ai.Size() != bi.Size() {
+		return false, nil
+	}
+	ah, err := partialHash(a)
+	if err != nil {
+		return false, err
+	}
+	bh, err := partialHash(b)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(ah, bh) {
+		return false, nil
+	}
+	return sameChecksum(a, b)
+}
+
+//sameChecksum returns whether the files at a and b have the same checksum
+func sameChecksum(a, b string) (bool, error) {
+	ah, err := checksum(a)
+	if err != nil {
+		return false, err
+	}
+	bh, err := checksum(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ah, bh), nil
+}
+
+//dedupe removes files with duplicate content from the scanned
+//set, keeping the first path of each, then applies the policy
+func (fs *fsSort) dedupe() error {
+	bySize := map[int64][]string{}
+	for path, file := range fs.sorts {
+		bySize[file.info.Size()] = append(bySize[file.info.Size()], path)
+	}
+	for _, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		byHash := map[string][]string{}
+		order := []string{}
+		for _, path := range paths {
+			hash := partialHash
+			if fs.FullHash {
+				hash = checksum
+			}
+			h, err := hash(path)
+			if err != nil {
+				return err
+			}
+			key := string(h)
+			if _, ok := byHash[key]; !ok {
+				order = append(order, key)
+			}
+			byHash[key] = append(byHash[key], path)
+		}
+		for _, key := range order {
+			group := byHash[key]
+			//partial hashes only sample the files,
+			//so confirm each dupe before acting on it
+			for len(group) > 1 {
+				original, rest := group[0], []string{}
+				for _, dupe := range group[1:] {
+					if !fs.FullHash {
+						same, err := sameChecksum(original, dupe)
+						if err != nil {
+							return err
+						}
+						if !same {
+							rest = append(rest, dupe)
+							continue
+						}
+					}
+					delete(fs.sorts, dupe)
+					if err := fs.handleDupe(dupe, original); err != nil {
+						log.Printf("%s\n  └─> %s", color.RedString(dupe), err)
+					}
+				}
+				group = rest
+			}
+		}
+	}
+	return nil
+}
+
+//handleDupe applies the policy to dupe, a duplicate of original
+func (fs *fsSort) handleDupe(dupe, original string) error {
+	if fs.DryRun {
+		log.Printf("Duplicate %s\n  └─> %s (%s)", color.YellowString(dupe), original, fs.Dupes)
+		return nil
+	}
+	info, err := os.Stat(dupe)
+	if err != nil {
+		return err
+	}
+	//undo restores the dupe from the original
+	op := Operation{Src: abs(original), Dst: abs(dupe), Size: info.Size()}
+	switch fs.Dupes {
+	case DeleteDupes:
+		if err := os.Remove(dupe); err != nil {
+			return err
+		}
+		op.Action = DeleteDupeAction
+		fs.record(op)
+	case LinkDupes:
+		//link beside the dupe, then atomically replace it
+		tmp, err := ioutil.TempFile(filepath.Dir(dupe), ".media-sort-*.tmp")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		os.Remove(tmp.Name())
+		if err := os.Link(original, tmp.Name()); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), dupe); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		op.Action = LinkDupeAction
+		fs.record(op)
+	}
+	log.Printf("Duplicate %s\n  └─> %s (%s)", color.YellowString(dupe), original, fs.Dupes)
+	return nil
+}
@@ -0,0 +1,93 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	data := strings.Repeat("0123456789", 20000)
+	files := map[string]string{
+		"a/Movie.2010.mkv": data,
+		"b/Movie.2010.mkv": data,
+		//same size, start and middle, different end
+		"c/Movie.2010.mkv": data[:len(data)-1] + "x",
+		//same size, start and end, different middle
+		"d/Movie.2010.mkv": data[:len(data)/2] + "x" + data[len(data)/2+1:],
+	}
+	fs := testSort()
+	fs.Dupes = LinkDupes
+	fs.JournalDir = filepath.Join(dir, "journal")
+	fs.journal = newJournal(fs.JournalDir)
+	fs.sorts = map[string]*fileSort{}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, _ := os.Stat(path)
+		fs.sorts[path] = &fileSort{path: path, info: info}
+	}
+	if err := fs.dedupe(); err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := filepath.Join(dir, "a/Movie.2010.mkv"), filepath.Join(dir, "b/Movie.2010.mkv"), filepath.Join(dir, "c/Movie.2010.mkv")
+	d := filepath.Join(dir, "d/Movie.2010.mkv")
+	if len(fs.sorts) != 3 || fs.sorts[a] == nil || fs.sorts[c] == nil || fs.sorts[d] == nil {
+		t.Fatalf("unexpected sorts: %v", fs.sorts)
+	}
+	ai, _ := os.Stat(a)
+	bi, _ := os.Stat(b)
+	if !os.SameFile(ai, bi) {
+		t.Fatal("expected duplicate to be linked")
+	}
+	if same, err := sameContent(a, c); err != nil || same {
+		t.Fatalf("expected different content: %v", err)
+	}
+	//undo restores the duplicate as a separate file
+	if err := fs.closeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Undo(UndoConfig{JournalDir: fs.JournalDir}); err != nil {
+		t.Fatal(err)
+	}
+	bi, _ = os.Stat(b)
+	if os.SameFile(ai, bi) {
+		t.Fatal("expected duplicate to be restored")
+	}
+	if same, err := sameContent(a, b); err != nil || !same {
+		t.Fatalf("expected restored content: %v", err)
+	}
+}
+
+func TestDeleteDupeUndo(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "a/Movie.2010.mkv", "b/Movie.2010.mkv")
+	a, b := filepath.Join(dir, "a/Movie.2010.mkv"), filepath.Join(dir, "b/Movie.2010.mkv")
+	fs := testSort()
+	fs.Dupes = DeleteDupes
+	fs.JournalDir = filepath.Join(dir, "journal")
+	fs.journal = newJournal(fs.JournalDir)
+	if err := fs.handleDupe(b, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Fatal("expected duplicate to be deleted")
+	}
+	if err := fs.closeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Undo(UndoConfig{JournalDir: fs.JournalDir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(b); err != nil {
+		t.Fatalf("expected duplicate to be restored: %s", err)
+	}
+}
@@ -0,0 +1,92 @@
+package mediasort
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+//parseAttrs parses the owner, group and modes which
+//are applied to sorted files and created directories
+func (fs *fsSort) parseAttrs() error {
+	var err error
+	fs.uid, fs.gid = -1, -1
+	fs.chown = fs.Owner != "" || fs.Group != ""
+	if fs.Owner != "" {
+		if fs.uid, err = lookupID(fs.Owner, func(s string) (string, error) {
+			u, err := user.Lookup(s)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}); err != nil {
+			return fmt.Errorf("Invalid owner: %s", err)
+		}
+	}
+	if fs.Group != "" {
+		if fs.gid, err = lookupID(fs.Group, func(s string) (string, error) {
+			g, err := user.LookupGroup(s)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}); err != nil {
+			return fmt.Errorf("Invalid group: %s", err)
+		}
+	}
+	if fs.fileMode, err = parseMode(fs.FileMode); err != nil {
+		return fmt.Errorf("Invalid file mode: %s", err)
+	}
+	if fs.dirMode, err = parseMode(fs.DirMode); err != nil {
+		return fmt.Errorf("Invalid dir mode: %s", err)
+	}
+	return nil
+}
+
+//lookupID returns the numeric id s, or looks up the id of name s
+func lookupID(s string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		return id, nil
+	}
+	id, err := lookup(s)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+//parseMode parses an octal mode, 0 is unset
+func parseMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if m > 0777 {
+		return 0, fmt.Errorf("%s is not a permission", s)
+	}
+	return os.FileMode(m), nil
+}
+
+//setAttrs applies the configured owner and mode
+//to the created file or directory at path
+func (fs *fsSort) setAttrs(path string, dir bool) error {
+	mode := fs.fileMode
+	if dir {
+		mode = fs.dirMode
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	if fs.chown {
+		if err := os.Lchown(path, fs.uid, fs.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+// +build darwin freebsd netbsd
+
+package mediasort
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+//accessTime returns the atime of info
+func accessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+	}
+	return info.ModTime()
+}
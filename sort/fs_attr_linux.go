@@ -0,0 +1,15 @@
+package mediasort
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+//accessTime returns the atime of info
+func accessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return info.ModTime()
+}
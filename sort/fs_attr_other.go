@@ -0,0 +1,13 @@
+// +build !linux,!darwin,!freebsd,!netbsd
+
+package mediasort
+
+import (
+	"os"
+	"time"
+)
+
+//accessTime is unavailable, use the mtime
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,12 @@
+package mediasort
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+//cloneFile reflinks the contents of src into dst (FICLONE)
+func cloneFile(src, dst *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
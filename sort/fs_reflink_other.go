@@ -0,0 +1,14 @@
+// +build !linux
+
+package mediasort
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+//cloneFile is unsupported on this platform
+func cloneFile(src, dst *os.File) error {
+	return errors.New("Reflinks are not supported on " + runtime.GOOS)
+}
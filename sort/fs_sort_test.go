@@ -0,0 +1,263 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+//touch creates empty files (and their directories) inside dir
+func touch(t *testing.T, dir string, paths ...string) {
+	for _, p := range paths {
+		p = filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "media-sort-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func testSort() *fsSort {
+	return &fsSort{validExts: map[string]bool{".mkv": true, ".mp4": true}}
+}
+
+func TestFindCompanions(t *testing.T) {
+	for _, tc := range []struct {
+		Files  []string
+		Video  string
+		Movie  bool
+		Expect []string
+	}{
+		{
+			//release folder
+			[]string{
+				"rel/Movie.2010.1080p.mkv",
+				"rel/Movie.2010.1080p.en.srt",
+				"rel/Movie.2010.1080p.en.forced.srt",
+				"rel/Movie.2010.1080p.nfo",
+				"rel/Movie.2010.1080p-sample.mkv",
+				"rel/poster.jpg",
+				"rel/readme.txt",
+				"rel/Subs/3_eng.srt",
+				"rel/Subs/4_fre.sdh.srt",
+				"rel/Subs/5_spa.sub",
+				"rel/Subs/5_spa.idx",
+			},
+			"rel/Movie.2010.1080p.mkv",
+			true,
+			[]string{
+				"Movie (2010).en.forced.srt",
+				"Movie (2010).en.2.srt",
+				"Movie (2010).en.srt",
+				"Movie (2010).es.idx",
+				"Movie (2010).es.sub",
+				"Movie (2010).fr.sdh.srt",
+				"Movie (2010).nfo",
+				"poster.jpg",
+			},
+		},
+		{
+			//season pack, only matching names are companions
+			[]string{
+				"pack/Show.S01E01.mkv",
+				"pack/Show.S01E01.ass",
+				"pack/Show.S01E02.mkv",
+				"pack/Show.S01E02.srt",
+				"pack/Show.S01E01-thumb.jpg",
+				"pack/Show.S01E01-trailer.nfo",
+				"pack/Show.S01E01.Part2.en.srt",
+				"pack/Show.S01E01.hi.srt",
+				"pack/Show.S01E01.en.hi.srt",
+				"pack/poster.jpg",
+				"pack/Subs/Show.S01E01/2_English.srt",
+				"pack/Subs/Show.S01E01/3_ger.srt",
+				"pack/Subs/Show.S01E02/2_English.srt",
+			},
+			"pack/Show.S01E01.mkv",
+			false,
+			[]string{
+				"Show S01E01-thumb.jpg",
+				"Show S01E01.ass",
+				"Show S01E01.de.srt",
+				"Show S01E01.hi.srt",
+				"Show S01E01.en.sdh.srt",
+				"Show S01E01.en.srt",
+			},
+		},
+	} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		touch(t, dir, tc.Files...)
+		fs := testSort()
+		companions, err := fs.findCompanions(filepath.Join(dir, tc.Video), tc.Movie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newVideo := "Movie (2010).mkv"
+		if !tc.Movie {
+			newVideo = "Show S01E01.mkv"
+		}
+		got := []string{}
+		for _, p := range companionPaths(companions, filepath.Join("lib", newVideo)) {
+			got = append(got, filepath.Base(p))
+		}
+		sort.Strings(got)
+		sort.Strings(tc.Expect)
+		if strings.Join(got, "\n") != strings.Join(tc.Expect, "\n") {
+			t.Errorf("%s\ngot:\n%s\nexp:\n%s", tc.Video, strings.Join(got, "\n"), strings.Join(tc.Expect, "\n"))
+		}
+	}
+}
+
+func TestNFOFiles(t *testing.T) {
+	base := filepath.Join("media", "tv")
+	r := &Result{Name: "The Office", MType: "series", Season: 2, Episode: 3, TVDBID: 73244, IMDBID: "tt0386676"}
+	files, err := r.nfoFiles(base, filepath.Join(base, "The Office", "Season 02", "The Office - S02E03.mkv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	show, ok := files[filepath.Join(base, "The Office", "tvshow.nfo")].(tvshowNFO)
+	if !ok {
+		t.Fatalf("missing tvshow.nfo: %v", files)
+	}
+	if len(show.UniqueIDs) != 2 || show.UniqueIDs[0].Type != "tvdb" || !show.UniqueIDs[0].Default {
+		t.Fatalf("unexpected unique ids: %+v", show.UniqueIDs)
+	}
+	if _, ok := files[filepath.Join(base, "The Office", "Season 02", "The Office - S02E03.nfo")].(episodeNFO); !ok {
+		t.Fatalf("missing episode nfo: %v", files)
+	}
+	//flat movies are named after their file, extras have none
+	m := &Result{Name: "Inception", MType: "movie", Year: "2010", TMDBID: 27205}
+	files, _ = m.nfoFiles("movies", filepath.Join("movies", "Inception (2010).mkv"))
+	if _, ok := files[filepath.Join("movies", "Inception (2010).nfo")]; !ok {
+		t.Fatalf("missing movie nfo: %v", files)
+	}
+	files, _ = m.nfoFiles("movies", filepath.Join("movies", "Inception (2010)", "Inception (2010).mkv"))
+	if _, ok := files[filepath.Join("movies", "Inception (2010)", "movie.nfo")]; !ok {
+		t.Fatalf("missing movie.nfo: %v", files)
+	}
+	m.Extra = "trailer"
+	if files, _ = m.nfoFiles("movies", filepath.Join("movies", "Inception (2010)-trailer.mkv")); len(files) != 0 {
+		t.Fatalf("unexpected extra nfo: %v", files)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src.mkv")
+	data := []byte(strings.Repeat("media-sort", 10000))
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2010, 7, 16, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0600); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst.mkv")
+	if err := copy(src, dst, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(dst); err != nil || string(b) != string(data) {
+		t.Fatalf("copy mismatch: %s", err)
+	}
+	//the mode and times are preserved
+	if info, err := os.Stat(dst); err != nil || !info.ModTime().Equal(mtime) || info.Mode().Perm() != 0600 {
+		t.Fatalf("copy attributes mismatch: %v %v", info.ModTime(), info.Mode())
+	}
+	//no temporary files remain, even after a failure
+	if err := copy(filepath.Join(dir, "missing.mkv"), filepath.Join(dir, "other.mkv"), true); err == nil {
+		t.Fatal("expected copy error")
+	}
+	infos, _ := ioutil.ReadDir(dir)
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(infos))
+	}
+	//rename errors are no longer ignored
+	if err := move(filepath.Join(dir, "missing.mkv"), dst, false); err == nil {
+		t.Fatal("expected move error")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for s, expect := range map[string]os.FileMode{"": 0, "0644": 0644, "775": 0775} {
+		if m, err := parseMode(s); err != nil || m != expect {
+			t.Fatalf("parseMode(%q) = %o, %v", s, m, err)
+		}
+	}
+	for _, s := range []string{"0999", "rwx", "01777"} {
+		if _, err := parseMode(s); err == nil {
+			t.Fatalf("parseMode(%q) expected error", s)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "src.mkv")
+	fs := testSort()
+	fs.Action = ReflinkAction
+	fs.Fallback = "copy, reflink,bogus"
+	if err := fs.parseSteps(); err == nil {
+		t.Fatal("expected unknown fallback error")
+	}
+	fs.Fallback = "copy, reflink"
+	if err := fs.parseSteps(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.steps) != 2 || fs.steps[1].String() != "copy" {
+		t.Fatalf("unexpected steps: %v", fs.steps)
+	}
+	//reflinks are unsupported by most file systems,
+	//either way, the file must be sorted
+	used, err := fs.action(filepath.Join(dir, "src.mkv"), filepath.Join(dir, "dst.mkv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used.action != ReflinkAction && used.action != CopyAction {
+		t.Fatalf("unexpected step: %s", used)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dst.mkv")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSymlinkTarget(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "downloads/Movie.2010.mkv", "library/Movie (2010)/.keep")
+	src := filepath.Join(dir, "downloads", "Movie.2010.mkv")
+	dst := filepath.Join(dir, "library", "Movie (2010)", "Movie (2010).mkv")
+	if err := link(src, dst, symLink, RelativeSymlink); err != nil {
+		t.Fatal(err)
+	}
+	target, err := os.Readlink(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := filepath.Join("..", "..", "downloads", "Movie.2010.mkv")
+	if target != expect {
+		t.Fatalf("expected %s, got %s", expect, target)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatalf("broken symlink: %s", err)
+	}
+}
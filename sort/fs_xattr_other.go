@@ -0,0 +1,6 @@
+// +build !linux,!darwin,!freebsd,!netbsd
+
+package mediasort
+
+//copyXattrs is unsupported on this platform
+func copyXattrs(src, dst string) {}
@@ -0,0 +1,37 @@
+// +build linux darwin freebsd netbsd
+
+package mediasort
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+//copyXattrs copies the extended attributes of src onto dst,
+//attributes which can't be read or written are skipped
+func copyXattrs(src, dst string) {
+	size, err := unix.Listxattr(src, nil)
+	if err != nil || size <= 0 {
+		return
+	}
+	names := make([]byte, size)
+	if size, err = unix.Listxattr(src, names); err != nil {
+		return
+	}
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		n, err := unix.Getxattr(src, attr, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, n)
+		if n, err = unix.Getxattr(src, attr, value); err != nil {
+			continue
+		}
+		unix.Setxattr(dst, attr, value[:n], 0)
+	}
+}
@@ -0,0 +1,140 @@
+package mediasort
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+//hookEnvPrefix prefixes the environment variables given to hooks
+const hookEnvPrefix = "MEDIA_SORT_"
+
+//env describes a sorted file to an --exec-after hook
+func (file *fileSort) env() []string {
+	r := file.result
+	vars := map[string]string{
+		"SOURCE":      r.Path,
+		"DESTINATION": file.dest,
+		"ACTION":      string(file.used.action),
+		"TYPE":        r.MType,
+		"TITLE":       r.Name,
+		"YEAR":        r.Year,
+		"ACCURACY":    strconv.Itoa(r.Accuracy),
+		"IMDB_ID":     r.IMDBID,
+	}
+	if r.TMDBID != 0 {
+		vars["TMDB_ID"] = strconv.Itoa(r.TMDBID)
+	}
+	if r.TVDBID != 0 {
+		vars["TVDB_ID"] = strconv.Itoa(r.TVDBID)
+	}
+	if r.MType == string(mediasearch.Series) {
+		vars["SEASON"] = strconv.Itoa(r.Season)
+		if r.Episode != -1 {
+			vars["EPISODE"] = strconv.Itoa(r.Episode)
+		}
+		if r.ExtraEpisode != -1 {
+			vars["EXTRA_EPISODE"] = strconv.Itoa(r.ExtraEpisode)
+		}
+		if r.EpisodeDate != "" {
+			vars["EPISODE_DATE"] = r.EpisodeDate
+		}
+	}
+	return hookEnv(vars)
+}
+
+//runEnv describes the run to an --exec-after-run hook
+func (fs *fsSort) runEnv() []string {
+	sorted, skipped, failed := fs.counts()
+	dests := []string{}
+	for _, file := range fs.sorts {
+		if file.dest != "" {
+			dests = append(dests, file.dest)
+		}
+	}
+	vars := map[string]string{
+		"SORTED":       strconv.Itoa(sorted),
+		"SKIPPED":      strconv.Itoa(skipped),
+		"FAILED":       strconv.Itoa(failed),
+		"DESTINATIONS": strings.Join(dests, "\n"),
+	}
+	if fs.journal != nil {
+		vars["RUN_ID"] = fs.journal.runID
+	}
+	return hookEnv(vars)
+}
+
+func hookEnv(vars map[string]string) []string {
+	env := []string{}
+	for k, v := range vars {
+		env = append(env, hookEnvPrefix+k+"="+v)
+	}
+	return env
+}
+
+//runHook runs the hook command in a shell with the given
+//environment, killing it when the timeout is exceeded
+func (fs *fsSort) runHook(command string, env []string) error {
+	cmd := hookCommand(command)
+	cmd.Env = append(os.Environ(), env...)
+	//hook output is shown with the logs
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	if fs.ExecTimeout <= 0 {
+		return <-done
+	}
+	timer := time.NewTimer(fs.ExecTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		killHook(cmd)
+		<-done
+		return fmt.Errorf("Timed out after %s", fs.ExecTimeout)
+	}
+}
+
+//hookFailed records a hook failure for the run summary
+func (fs *fsSort) hookFailed(hook, path string, err error) {
+	msg := fmt.Sprintf("%s %s (%s)", hook, path, err)
+	if path == "" {
+		msg = fmt.Sprintf("%s (%s)", hook, err)
+	}
+	fs.hookLock.Lock()
+	fs.hookErrors = append(fs.hookErrors, msg)
+	fs.hookLock.Unlock()
+}
+
+//execAfter runs the --exec-after hook for a sorted file
+func (fs *fsSort) execAfter(file *fileSort) {
+	if fs.ExecAfter == "" || fs.DryRun || file.dest == "" {
+		return
+	}
+	fs.verbf("exec-after %s", file.dest)
+	if err := fs.runHook(fs.ExecAfter, file.env()); err != nil {
+		fs.hookFailed("exec-after", file.dest, err)
+	}
+}
+
+//execAfterRun runs the --exec-after-run hook once all files are sorted
+func (fs *fsSort) execAfterRun() {
+	if fs.ExecAfterRun == "" || fs.DryRun {
+		return
+	}
+	fs.verbf("exec-after-run")
+	if err := fs.runHook(fs.ExecAfterRun, fs.runEnv()); err != nil {
+		fs.hookFailed("exec-after-run", "", err)
+	}
+}
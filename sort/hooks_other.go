@@ -0,0 +1,19 @@
+// +build windows plan9
+
+package mediasort
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+func hookCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("rc", "-c", command)
+}
+
+func killHook(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,44 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	fs := testSort()
+	fs.ExecAfter = `echo "$MEDIA_SORT_TITLE S$MEDIA_SORT_SEASON E$MEDIA_SORT_EPISODE $MEDIA_SORT_ACTION $MEDIA_SORT_DESTINATION" > ` + out
+	file := &fileSort{
+		path:   "Dark.S01E02.mkv",
+		result: &Result{Name: "Dark", MType: "series", Season: 1, Episode: 2, ExtraEpisode: -1},
+		dest:   "tv/Dark S01E02.mkv",
+		used:   step{action: MoveAction},
+	}
+	fs.execAfter(file)
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "Dark S1 E2 move tv/Dark S01E02.mkv" {
+		t.Fatalf("unexpected hook output: %s", got)
+	}
+	//failures and timeouts are reported in the summary
+	fs.sorts = map[string]*fileSort{file.path: file}
+	fs.ExecAfterRun = `test "$MEDIA_SORT_SORTED" = 1 && sleep 5`
+	fs.ExecTimeout = 100 * time.Millisecond
+	fs.execAfterRun()
+	if len(fs.hookErrors) != 1 || !strings.Contains(fs.hookErrors[0], "Timed out") {
+		t.Fatalf("unexpected hook errors: %v", fs.hookErrors)
+	}
+}
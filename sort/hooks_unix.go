@@ -0,0 +1,20 @@
+// +build !windows,!plan9
+
+package mediasort
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+//hookCommand runs command in its own process group,
+//so the whole group can be killed
+func hookCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func killHook(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,155 @@
+package mediasort
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+const (
+	//candidates within this accuracy of the best are ambiguous
+	closeAccuracy = 5
+	//maximum number of candidates shown
+	maxCandidates = 5
+)
+
+//candidates searches for all possible matches (replaced in tests)
+var candidates = mediasearch.Candidates
+
+var errSkipped = errors.New("Skipped")
+
+//choice is an interactive choice, remembered for the rest of the run
+type choice struct {
+	result mediasearch.Result
+	skip   bool
+}
+
+//interactiveSort sorts the file at path, asking the user to choose when
+//the best match is below the threshold, or when the best two are close
+func (fs *fsSort) interactiveSort(file *fileSort) (Result, error) {
+	result, err := runPathParse(file.path, fs.NumDirs)
+	if err != nil {
+		return result, err
+	}
+	if ok, err := result.overridePath(); ok || err != nil {
+		return result, err
+	}
+	//previous choice for this query
+	if c, ok := fs.choice(result.Query); ok {
+		return result, result.use(c)
+	}
+	results, err := candidates(result.Query, result.Year, result.MType)
+	if err == nil && len(results) > 0 && results[0].Accuracy >= fs.AccuracyThreshold && !ambiguous(results) {
+		result.apply(results[0])
+		return result, nil
+	}
+	fs.promptLock.Lock()
+	defer fs.promptLock.Unlock()
+	//another file with this query may have been chosen while waiting
+	if c, ok := fs.choices[result.Query]; ok {
+		return result, result.use(c)
+	}
+	c, err := fs.ask(file, result, results, err)
+	if err != nil {
+		return result, err
+	}
+	fs.choices[result.Query] = c
+	//chosen results are used in future runs
+	if !c.skip && fs.Overrides != "" {
+		if err := mediasearch.SaveOverride(fs.Overrides, result.Query, mediasearch.OverrideOf(c.result)); err != nil {
+			log.Printf("Failed to save override: %s", err)
+		} else {
+			log.Printf("Saved override %s\n  └─> %s", color.CyanString(result.Query), color.GreenString(c.result.String()))
+		}
+	}
+	return result, result.use(c)
+}
+
+func (fs *fsSort) choice(query string) (choice, bool) {
+	fs.promptLock.Lock()
+	defer fs.promptLock.Unlock()
+	c, ok := fs.choices[query]
+	return c, ok
+}
+
+func (result *Result) use(c choice) error {
+	if c.skip {
+		return errSkipped
+	}
+	result.apply(c.result)
+	return nil
+}
+
+//ambiguous returns whether the best two candidates are close
+func ambiguous(results []mediasearch.Result) bool {
+	if len(results) < 2 {
+		return false
+	}
+	a, b := results[0], results[1]
+	return a.Accuracy-b.Accuracy <= closeAccuracy && (a.Title != b.Title || a.Year != b.Year)
+}
+
+//ask shows the candidates and reads the user's choice,
+//manual queries are searched and shown again
+func (fs *fsSort) ask(file *fileSort, result Result, results []mediasearch.Result, searchErr error) (choice, error) {
+	out := fs.promptOut
+	fmt.Fprintf(out, "\n[#%d/%d] %s\n", file.id, len(fs.sorts), color.CyanString(file.path))
+	for {
+		desc := result.Query
+		if result.MType != "" {
+			desc += " (" + result.MType + ")"
+		}
+		if result.Year != "" {
+			desc += " from " + result.Year
+		}
+		fmt.Fprintf(out, "  query: %s\n", desc)
+		if searchErr != nil {
+			fmt.Fprintf(out, "  %s\n", color.RedString(searchErr.Error()))
+		}
+		if len(results) > maxCandidates {
+			results = results[:maxCandidates]
+		}
+		for i, r := range results {
+			fmt.Fprintf(out, "  %d) %s %s (accuracy %d)\n", i+1, color.GreenString(r.String()), r.Type, r.Accuracy)
+		}
+		def := "s"
+		if len(results) > 0 {
+			def = "1"
+		}
+		fmt.Fprintf(out, "  m) manual query\n  s) skip\n  choose [%s]: ", def)
+		answer, err := fs.promptIn.ReadString('\n')
+		if err != nil && (err != io.EOF || answer == "") {
+			return choice{}, errSkipped
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			answer = def
+		}
+		switch answer {
+		case "s":
+			return choice{skip: true}, nil
+		case "m":
+			fmt.Fprint(out, "  query: ")
+			q, err := fs.promptIn.ReadString('\n')
+			if err != nil && (err != io.EOF || q == "") {
+				return choice{}, errSkipped
+			}
+			result.Query = strings.TrimSpace(q)
+			result.Year = ""
+			results, searchErr = candidates(result.Query, "", result.MType)
+			continue
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 1 || n > len(results) {
+			fmt.Fprintf(out, "  invalid choice: %s\n", answer)
+			continue
+		}
+		return choice{result: results[n-1]}, nil
+	}
+}
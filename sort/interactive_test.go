@@ -0,0 +1,65 @@
+package mediasort
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+func TestInteractiveSort(t *testing.T) {
+	searched := 0
+	defer func(c func(string, string, string) ([]mediasearch.Result, error)) { candidates = c }(candidates)
+	candidates = func(query, year, mediatype string) ([]mediasearch.Result, error) {
+		searched++
+		if query == "the office" {
+			return []mediasearch.Result{
+				{Title: "The Office", Year: "2005", Type: mediasearch.Series, Accuracy: 90},
+				{Title: "The Office", Year: "2001", Type: mediasearch.Series, Accuracy: 88},
+			}, nil
+		}
+		return []mediasearch.Result{{Title: "Dark", Year: "2017", Type: mediasearch.Series, Accuracy: 95}}, nil
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	defer mediasearch.LoadOverrides("")
+	fs := testSort()
+	fs.Overrides = filepath.Join(dir, "overrides.json")
+	fs.AccuracyThreshold = 80
+	fs.Interactive = true
+	fs.promptOut = ioutil.Discard
+	fs.choices = map[string]choice{}
+	//choose the second candidate
+	fs.promptIn = bufio.NewReader(strings.NewReader("2\n"))
+	r, err := fs.interactiveSort(&fileSort{id: 1, path: "The.Office.S01E01.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "The Office" || r.Year != "2001" {
+		t.Fatalf("unexpected choice: %s (%s)", r.Name, r.Year)
+	}
+	//choices are saved as overrides
+	b, err := ioutil.ReadFile(fs.Overrides)
+	if err != nil || !strings.Contains(string(b), `"the office"`) || !strings.Contains(string(b), `"2001"`) {
+		t.Fatalf("unexpected overrides: %s %v", b, err)
+	}
+	//choices are remembered for the same query
+	r, err = fs.interactiveSort(&fileSort{id: 2, path: "The.Office.S01E02.mkv"})
+	if err != nil || r.Year != "2001" || searched != 1 {
+		t.Fatalf("choice not remembered: %v %s %d", err, r.Year, searched)
+	}
+	//confident matches are not prompted
+	r, err = fs.interactiveSort(&fileSort{id: 3, path: "Dark.S01E01.mkv"})
+	if err != nil || r.Name != "Dark" {
+		t.Fatalf("unexpected result: %v %s", err, r.Name)
+	}
+	//no input skips
+	fs.choices = map[string]choice{}
+	if _, err := fs.interactiveSort(&fileSort{id: 4, path: "The.Office.S01E03.mkv"}); err != errSkipped {
+		t.Fatalf("expected skip, got %v", err)
+	}
+}
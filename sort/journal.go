@@ -0,0 +1,396 @@
+package mediasort
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+//Operation is a single file system change, journals
+//hold one json encoded operation per line
+type Operation struct {
+	Time   time.Time `json:"time"`
+	Action Action    `json:"action"`
+	//Link is set for link actions (hardLink|symLink)
+	Link string `json:"link,omitempty"`
+	Src  string `json:"src,omitempty"`
+	Dst  string `json:"dst"`
+	//Size of dst after the operation, used to detect changes before undoing
+	Size int64 `json:"size"`
+	//Companion operations belong to the preceding video
+	Companion bool `json:"companion,omitempty"`
+	//Overwrote is the size of the file which was replaced at dst,
+	//these files are gone and cannot be restored
+	Overwrote *int64 `json:"overwrote,omitempty"`
+	//Dirs created for dst, removed (when empty) on undo
+	Dirs []string `json:"dirs,omitempty"`
+}
+
+const (
+	//WriteAction creates a generated file (nfo files)
+	WriteAction Action = "write"
+	//DeleteDupeAction removes a duplicate of src
+	DeleteDupeAction Action = "delete-dupe"
+	//LinkDupeAction replaces a duplicate with a hardlink to src
+	LinkDupeAction Action = "link-dupe"
+)
+
+const journalExt = ".jsonl"
+
+//runIDLayout is the time layout of run IDs
+const runIDLayout = "20060102-150405"
+
+//DefaultJournalDir returns the directory which holds journals,
+//(eg. ~/.config/media-sort/journal) which is empty (runs are
+//not journaled) when there is no user config directory
+func DefaultJournalDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "media-sort", "journal")
+}
+
+//journal is an append-only log of the operations in a run
+type journal struct {
+	sync.Mutex
+	dir   string
+	runID string
+	file  *os.File
+}
+
+func newJournal(dir string) *journal {
+	return &journal{dir: dir, runID: time.Now().Format(runIDLayout)}
+}
+
+//record appends op to the journal, the journal file
+//is created on the first operation
+func (j *journal) record(op Operation) error {
+	j.Lock()
+	defer j.Unlock()
+	if j.file == nil {
+		if err := os.MkdirAll(j.dir, 0755); err != nil {
+			return err
+		}
+		//runs started in the same second are numbered
+		base := j.runID
+		for n := 2; ; n++ {
+			f, err := os.OpenFile(filepath.Join(j.dir, j.runID+journalExt), os.O_CREATE|os.O_EXCL|os.O_APPEND|os.O_WRONLY, 0644)
+			if err == nil {
+				j.file = f
+				break
+			}
+			if !os.IsExist(err) {
+				return err
+			}
+			j.runID = fmt.Sprintf("%s-%d", base, n)
+		}
+	}
+	op.Time = time.Now()
+	b, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	//flush each line, the journal must survive a crash
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	j.Lock()
+	defer j.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+//mkdirAll is os.MkdirAll, which also returns
+//the directories it created, parents first
+func mkdirAll(dir string) ([]string, error) {
+	missing := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+//newOperation describes the completed action from src to dst
+func (fs *fsSort) newOperation(s step, src, dst string, overwrote os.FileInfo, dirs []string) Operation {
+	op := Operation{Action: s.action, Link: string(s.link), Src: abs(src), Dst: abs(dst), Size: -1}
+	if info, err := os.Lstat(dst); err == nil {
+		op.Size = info.Size()
+	}
+	if overwrote != nil {
+		size := overwrote.Size()
+		op.Overwrote = &size
+	}
+	for _, d := range dirs {
+		op.Dirs = append(op.Dirs, abs(d))
+	}
+	return op
+}
+
+//record the operation in the run's journal, a
+//failure to record is logged and does not abort the sort
+func (fs *fsSort) record(op Operation) {
+	if fs.journal == nil {
+		return
+	}
+	if err := fs.journal.record(op); err != nil {
+		log.Printf("Failed to write journal: %s", err)
+	}
+	fs.originsLock.Lock()
+	if fs.origins != nil {
+		fs.addOrigin(op)
+	}
+	fs.originsLock.Unlock()
+}
+
+func abs(path string) string {
+	if p, err := filepath.Abs(path); err == nil {
+		return p
+	}
+	return path
+}
+
+//UndoConfig is an undo configuration
+type UndoConfig struct {
+	//RunID is optional, the latest run is undone by default
+	RunID      []string `opts:"mode=arg,max=1"`
+	JournalDir string   `opts:"help=directory containing the journals of each run"`
+	List       bool     `opts:"help=list runs instead of undoing"`
+	DryRun     bool     `opts:"help=show the operations which would be reverted"`
+}
+
+//Undo reverts the operations of a run in reverse order. Files which
+//have changed since the run are conflicts, and are left untouched.
+func Undo(c UndoConfig) error {
+	if c.JournalDir == "" {
+		c.JournalDir = DefaultJournalDir()
+	}
+	if c.JournalDir == "" {
+		return errors.New("No journal directory (there is no user config directory)")
+	}
+	runs, err := listRuns(c.JournalDir)
+	if err != nil {
+		return err
+	}
+	if c.List {
+		for _, r := range runs {
+			log.Println(r)
+		}
+		return nil
+	}
+	runID := ""
+	if len(c.RunID) > 0 {
+		runID = strings.TrimSuffix(filepath.Base(c.RunID[0]), journalExt)
+	}
+	if runID == "" {
+		if len(runs) == 0 {
+			return fmt.Errorf("No runs found in %s", c.JournalDir)
+		}
+		runID = runs[len(runs)-1]
+	}
+	path := filepath.Join(c.JournalDir, runID+journalExt)
+	ops, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+	if c.DryRun {
+		log.Println(color.CyanString("[Dryrun]"))
+	}
+	conflicts := 0
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if err := undoOperation(op, c.DryRun); err != nil {
+			conflicts++
+			log.Printf("%s\n  └─> %s", color.RedString(op.Dst), err)
+			continue
+		}
+		if op.Src == "" {
+			log.Printf("Removed %s", color.GreenString(op.Dst))
+		} else if op.Action == DeleteDupeAction || op.Action == LinkDupeAction {
+			log.Printf("Restored duplicate %s", color.GreenString(op.Dst))
+		} else {
+			log.Printf("%s\n  └─> %s", color.GreenString(op.Dst), color.GreenString(op.Src))
+		}
+		if op.Overwrote != nil {
+			log.Printf("  └─> %s", color.YellowString("the file previously at this path was overwritten and cannot be restored"))
+		}
+	}
+	if c.DryRun {
+		return nil
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("Undo of run %s has %d conflicts, these files were left untouched", runID, conflicts)
+	}
+	//fully reverted, the journal is no longer needed
+	return os.Remove(path)
+}
+
+//listRuns returns the IDs of the journaled runs, oldest first
+func listRuns(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	runs := []string{}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), journalExt) {
+			runs = append(runs, strings.TrimSuffix(info.Name(), journalExt))
+		}
+	}
+	//oldest first, by start time then number
+	sort.Slice(runs, func(i, j int) bool {
+		ti, ni := runOrder(runs[i])
+		tj, nj := runOrder(runs[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return ni < nj
+	})
+	return runs, nil
+}
+
+//runOrder splits a run ID into its start time and number,
+//the first run started in a second has no number
+func runOrder(runID string) (string, int) {
+	if i := len(runIDLayout); len(runID) > i && runID[i] == '-' {
+		if n, err := strconv.Atoi(runID[i+1:]); err == nil {
+			return runID[:i], n
+		}
+	}
+	return runID, 1
+}
+
+func readJournal(path string) ([]Operation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ops := []Operation{}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		op := Operation{}
+		if err := json.Unmarshal(s.Bytes(), &op); err != nil {
+			//the last line of a crashed run may be incomplete
+			log.Printf("Skipping invalid journal entry: %s", err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, s.Err()
+}
+
+//undoOperation reverts op, returning an error on conflict
+func undoOperation(op Operation, dryRun bool) error {
+	if op.Action == DeleteDupeAction || op.Action == LinkDupeAction {
+		return restoreDupe(op, dryRun)
+	}
+	info, err := os.Lstat(op.Dst)
+	if err != nil {
+		return errors.New("File no longer exists")
+	}
+	if op.Size >= 0 && info.Size() != op.Size {
+		return errors.New("File has changed since it was sorted")
+	}
+	switch op.Action {
+	case MoveAction:
+		if _, err := os.Lstat(op.Src); err == nil {
+			return fmt.Errorf("File already exists '%s'", op.Src)
+		}
+	case CopyAction, ReflinkAction:
+		if _, err := os.Stat(op.Src); err != nil {
+			return fmt.Errorf("Original file is missing '%s'", op.Src)
+		}
+	case LinkAction:
+		if op.Link == string(symLink) && info.Mode()&os.ModeSymlink == 0 {
+			return errors.New("File is no longer a symlink")
+		}
+		if op.Link == string(hardLink) {
+			if srcInfo, err := os.Stat(op.Src); err != nil || !os.SameFile(srcInfo, info) {
+				return fmt.Errorf("File is no longer linked to '%s'", op.Src)
+			}
+		}
+	case WriteAction:
+	default:
+		return fmt.Errorf("Unknown action: %s", op.Action)
+	}
+	if dryRun {
+		return nil
+	}
+	if op.Action == MoveAction {
+		if err := os.MkdirAll(filepath.Dir(op.Src), 0755); err != nil {
+			return err
+		}
+		if err := move(op.Dst, op.Src, false); err != nil {
+			return err
+		}
+	} else if err := os.Remove(op.Dst); err != nil {
+		return err
+	}
+	//remove created directories, deepest first, while empty
+	for i := len(op.Dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(op.Dirs[i]); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+//restoreDupe copies the original back over a duplicate which was
+//deleted or linked, the dupe had the same content as the original
+func restoreDupe(op Operation, dryRun bool) error {
+	if op.Action == DeleteDupeAction {
+		if _, err := os.Lstat(op.Dst); err == nil {
+			return fmt.Errorf("File already exists '%s'", op.Dst)
+		}
+	} else {
+		info, err := os.Lstat(op.Dst)
+		if err != nil {
+			return errors.New("File no longer exists")
+		}
+		if srcInfo, err := os.Stat(op.Src); err != nil || !os.SameFile(srcInfo, info) {
+			return fmt.Errorf("File is no longer linked to '%s'", op.Src)
+		}
+	}
+	if info, err := os.Stat(op.Src); err != nil || info.Size() != op.Size {
+		return fmt.Errorf("Original file is missing or has changed '%s'", op.Src)
+	}
+	if dryRun {
+		return nil
+	}
+	//the directory may have been cleaned up
+	if err := os.MkdirAll(filepath.Dir(op.Dst), 0755); err != nil {
+		return err
+	}
+	return copy(op.Src, op.Dst, false)
+}
@@ -0,0 +1,97 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Movie.2010.mkv", "in/Movie.2010.en.srt", "in/Other.mkv")
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out", "Movie (2010)")
+	fs := testSort()
+	fs.Action = MoveAction
+	fs.journal = newJournal(filepath.Join(dir, "journal"))
+	//sort a video and its subtitles
+	sortTo := func(src, dst string, companion bool) {
+		dirs, err := mkdirAll(filepath.Dir(dst))
+		if err != nil {
+			t.Fatal(err)
+		}
+		used, err := fs.action(src, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := fs.newOperation(used, src, dst, nil, dirs)
+		op.Companion = companion
+		fs.record(op)
+	}
+	sortTo(filepath.Join(in, "Movie.2010.mkv"), filepath.Join(out, "Movie (2010).mkv"), false)
+	sortTo(filepath.Join(in, "Movie.2010.en.srt"), filepath.Join(out, "Movie (2010).en.srt"), true)
+	sortTo(filepath.Join(in, "Other.mkv"), filepath.Join(dir, "out", "Other.mkv"), false)
+	runID := fs.journal.runID
+	if err := fs.closeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	//the source of the last sort was replaced, a conflict
+	touch(t, dir, "in/Other.mkv")
+	c := UndoConfig{JournalDir: filepath.Join(dir, "journal")}
+	if err := Undo(c); err == nil {
+		t.Fatal("expected a conflict")
+	}
+	for _, p := range []string{"in/Movie.2010.mkv", "in/Movie.2010.en.srt", "out/Other.mkv"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Fatalf("expected %s to exist: %s", p, err)
+		}
+	}
+	//created directories are removed when empty
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed", out)
+	}
+	//conflicts keep the journal
+	if _, err := ioutil.ReadFile(filepath.Join(dir, "journal", runID+journalExt)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJournalRunIDs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	//runs started in the same second have their own journals
+	a, b := newJournal(dir), newJournal(dir)
+	b.runID = a.runID
+	for _, j := range []*journal{a, b} {
+		if err := j.record(Operation{Action: WriteAction, Dst: "a.nfo"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.runID == b.runID {
+		t.Fatalf("expected unique run ids, got %s", a.runID)
+	}
+	runs, err := listRuns(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0] != a.runID || runs[1] != b.runID {
+		t.Fatalf("unexpected runs: %v", runs)
+	}
+	//numbered runs are ordered numerically
+	for _, runID := range []string{a.runID + "-10", a.runID + "-3", "20000101-000000"} {
+		touch(t, dir, runID+journalExt)
+	}
+	if runs, err = listRuns(dir); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"20000101-000000", a.runID, b.runID, a.runID + "-3", a.runID + "-10"}
+	if strings.Join(runs, " ") != strings.Join(expect, " ") {
+		t.Fatalf("unexpected runs: %v", runs)
+	}
+}
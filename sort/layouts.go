@@ -0,0 +1,128 @@
+package mediasort
+
+import "fmt"
+
+//Layout names a set of preset path templates
+type Layout string
+
+const (
+	//FlatLayout places all files directly in the base directories (the default)
+	FlatLayout Layout = "flat"
+	//PlexLayout follows the Plex naming guide, shows and movies
+	//get their own folders tagged with {tvdb-ID} and {tmdb-ID}
+	PlexLayout Layout = "plex"
+	//JellyfinLayout follows the Jellyfin naming guide, folders
+	//are tagged with [tvdbid-ID] and [tmdbid-ID]
+	JellyfinLayout Layout = "jellyfin"
+	//EmbyLayout follows the Emby naming guide, folders
+	//are tagged with [tvdbid=ID] and [tmdbid=ID]
+	EmbyLayout Layout = "emby"
+	//KodiLayout follows the Kodi naming guide, Kodi identifies
+	//media using .nfo files instead of folder names
+	KodiLayout Layout = "kodi"
+)
+
+const (
+	episodeNumber = `S{{ printf "%02d" .Season }}E{{ printf "%02d" .Episode }}`
+	seasonFolder  = `Season {{ printf "%02d" .Season }}/`
+	//movieFile places extras in their own sub-folder
+	movieFile = `{{ with .Extra }}{{ extraDir . }}/{{ end }}` +
+		`{{ .Name }} ({{ .Year }}){{ with .Extra }}-{{ . }}{{ end }}.{{ .Ext }}`
+)
+
+//episodeFile returns the standard episode file name template given
+//the multi-episode separator (S01E01-E02 or S01E01E02)
+func episodeFile(multiSep string) string {
+	return `{{ if .EpisodeDate }}{{ .Name }} - {{ .EpisodeDate }}{{ else }}` +
+		`{{ .Name }} - ` + episodeNumber +
+		`{{ if ne .ExtraEpisode -1 }}` + multiSep + `{{ printf "%02d" .ExtraEpisode }}{{ end }}` +
+		`{{ end }}.{{ .Ext }}`
+}
+
+//showFolder and movieFolder return folder templates tagged with
+//provider IDs using the given format (e.g. " {%s-%s}")
+func showFolder(idFormat string) string {
+	return `{{ .Name }}` +
+		`{{ if .TVDBID }}` + fmt.Sprintf(idFormat, "tvdb", "{{ .TVDBID }}") +
+		`{{ else if .TMDBID }}` + fmt.Sprintf(idFormat, "tmdb", "{{ .TMDBID }}") +
+		`{{ else if .IMDBID }}` + fmt.Sprintf(idFormat, "imdb", "{{ .IMDBID }}") +
+		`{{ end }}/`
+}
+
+func movieFolder(idFormat string) string {
+	return `{{ .Name }} ({{ .Year }})` +
+		`{{ if .TMDBID }}` + fmt.Sprintf(idFormat, "tmdb", "{{ .TMDBID }}") +
+		`{{ else if .IMDBID }}` + fmt.Sprintf(idFormat, "imdb", "{{ .IMDBID }}") +
+		`{{ end }}/`
+}
+
+//Layouts contains the preset path templates for each Layout
+var Layouts = map[Layout]PathConfig{
+	FlatLayout: {
+		TVTemplate:    DefaultTVTemplate,
+		MovieTemplate: DefaultMovieTemplate,
+	},
+	PlexLayout: {
+		TVTemplate:    showFolder(" {%s-%s}") + seasonFolder + episodeFile("-E"),
+		MovieTemplate: movieFolder(" {%s-%s}") + movieFile,
+	},
+	JellyfinLayout: {
+		TVTemplate:    showFolder(" [%sid-%s]") + seasonFolder + episodeFile("-E"),
+		MovieTemplate: movieFolder(" [%sid-%s]") + movieFile,
+	},
+	EmbyLayout: {
+		TVTemplate:    showFolder(" [%sid=%s]") + seasonFolder + episodeFile("-E"),
+		MovieTemplate: movieFolder(" [%sid=%s]") + movieFile,
+	},
+	KodiLayout: {
+		TVTemplate: `{{ .Name }}/` + seasonFolder + episodeFile("E"),
+		//kodi expects trailers alongside the movie and other extras in "Extras"
+		MovieTemplate: `{{ .Name }} ({{ .Year }})/` +
+			`{{ if and .Extra (ne .Extra "trailer") }}Extras/{{ end }}` +
+			`{{ .Name }} ({{ .Year }}){{ with .Extra }}-{{ . }}{{ end }}.{{ .Ext }}`,
+	},
+}
+
+//withLayout returns a copy of the config with
+//empty templates set from its layout
+func (c PathConfig) withLayout() (PathConfig, error) {
+	if c.Layout == "" {
+		c.Layout = FlatLayout
+	}
+	preset, ok := Layouts[c.Layout]
+	if !ok {
+		return c, fmt.Errorf("Unknown layout: %s", c.Layout)
+	}
+	if c.Layout == FlatLayout {
+		//defaults may have been changed by the user
+		preset = PathConfig{TVTemplate: DefaultTVTemplate, MovieTemplate: DefaultMovieTemplate}
+	}
+	if c.TVTemplate == "" {
+		c.TVTemplate = preset.TVTemplate
+	}
+	if c.MovieTemplate == "" {
+		c.MovieTemplate = preset.MovieTemplate
+	}
+	return c, nil
+}
+
+//extraDirs are the folder names used by Plex,
+//Jellyfin and Emby for each type of extra
+var extraDirs = map[string]string{
+	"behindthescenes": "Behind The Scenes",
+	"deleted":         "Deleted Scenes",
+	"featurette":      "Featurettes",
+	"interview":       "Interviews",
+	"scene":           "Scenes",
+	"short":           "Shorts",
+	"trailer":         "Trailers",
+	"other":           "Other",
+}
+
+//extraDir returns the folder name for the given type of extra
+func extraDir(extra string) string {
+	if d, ok := extraDirs[extra]; ok {
+		return d
+	}
+	return "Other"
+}
@@ -0,0 +1,141 @@
+package mediasort
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+//nfo files are read by Kodi, Jellyfin and Emby, and
+//lock the media server onto the matched identity
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	ID      string `xml:",chardata"`
+}
+
+type movieNFO struct {
+	XMLName       xml.Name      `xml:"movie"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          string        `xml:"year,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+}
+
+type tvshowNFO struct {
+	XMLName       xml.Name      `xml:"tvshow"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          string        `xml:"year,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+}
+
+type episodeNFO struct {
+	XMLName   xml.Name `xml:"episodedetails"`
+	ShowTitle string   `xml:"showtitle"`
+	Season    int      `xml:"season"`
+	Episode   int      `xml:"episode,omitempty"`
+	Aired     string   `xml:"aired,omitempty"`
+}
+
+//uniqueIDs returns the provider IDs of the result, the
+//first is the default (tvdb for series, tmdb for movies)
+func (result *Result) uniqueIDs() []nfoUniqueID {
+	ids := []nfoUniqueID{}
+	add := func(t, id string) {
+		if id != "" && id != "0" {
+			ids = append(ids, nfoUniqueID{Type: t, Default: len(ids) == 0, ID: id})
+		}
+	}
+	if result.MType == string(mediasearch.Series) {
+		add("tvdb", strconv.Itoa(result.TVDBID))
+	}
+	add("tmdb", strconv.Itoa(result.TMDBID))
+	add("imdb", result.IMDBID)
+	return ids
+}
+
+//nfoFiles returns the nfo files (path and contents) which describe
+//the result, sorted into newPath. baseDir is the tv or movie directory.
+//Movies in their own folder get a movie.nfo, tv series in their own
+//folder get a tvshow.nfo, and episodes get a <name>.nfo.
+func (result *Result) nfoFiles(baseDir, newPath string) (map[string]interface{}, error) {
+	files := map[string]interface{}{}
+	rel, err := filepath.Rel(baseDir, newPath)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	name := strings.TrimSuffix(newPath, filepath.Ext(newPath)) + ".nfo"
+	switch mediasearch.MediaType(result.MType) {
+	case mediasearch.Movie:
+		if result.Extra != "" {
+			break //extras belong to their movie
+		}
+		if len(parts) > 1 {
+			name = filepath.Join(filepath.Dir(newPath), "movie.nfo")
+		}
+		files[name] = movieNFO{
+			Title:         result.Name,
+			OriginalTitle: result.OriginalTitle,
+			Year:          result.Year,
+			Plot:          result.Plot,
+			UniqueIDs:     result.uniqueIDs(),
+		}
+	case mediasearch.Series:
+		if len(parts) > 1 {
+			files[filepath.Join(baseDir, parts[0], "tvshow.nfo")] = tvshowNFO{
+				Title:         result.Name,
+				OriginalTitle: result.OriginalTitle,
+				Year:          result.Year,
+				Plot:          result.Plot,
+				UniqueIDs:     result.uniqueIDs(),
+			}
+		}
+		e := episodeNFO{ShowTitle: result.Name, Season: result.Season, Aired: result.EpisodeDate}
+		if result.Episode > 0 {
+			e.Episode = result.Episode
+		}
+		files[name] = e
+	}
+	return files, nil
+}
+
+//writeNFOs writes the result's nfo files, existing
+//files are only replaced when overwriting
+func (fs *fsSort) writeNFOs(result *Result, baseDir, newPath string) error {
+	files, err := result.nfoFiles(baseDir, newPath)
+	if err != nil {
+		return err
+	}
+	fs.nfoLock.Lock()
+	defer fs.nfoLock.Unlock()
+	for path, nfo := range files {
+		existing, err := os.Stat(path)
+		if err == nil && !fs.Overwrite {
+			fs.verbf("skip existing nfo: %s", path)
+			continue
+		}
+		b, err := xml.MarshalIndent(nfo, "", "  ")
+		if err != nil {
+			return err
+		}
+		b = append([]byte(xml.Header), b...)
+		if err := ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+		if err := fs.setAttrs(path, false); err != nil {
+			return err
+		}
+		fs.record(fs.newOperation(step{action: WriteAction}, "", path, existing, nil))
+		fs.verbf("wrote nfo: %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package mediasort
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+//notifyTimeout limits each request to a media server
+const notifyTimeout = 30 * time.Second
+
+//notifier tells a media server to rescan the
+//directories which files were sorted into
+type notifier interface {
+	name() string
+	notify(dirs []string) error
+}
+
+//notifiers returns the configured media server notifiers
+func (c *Config) notifiers() ([]notifier, error) {
+	client := &http.Client{Timeout: notifyTimeout}
+	ns := []notifier{}
+	if c.PlexURL != "" {
+		paths, err := parsePathMap(c.PlexPathMap)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, &plexNotifier{client: client, url: c.PlexURL, token: c.PlexToken, paths: paths})
+	}
+	if c.JellyfinURL != "" {
+		ns = append(ns, &jellyfinNotifier{client: client, url: c.JellyfinURL, token: c.JellyfinToken})
+	}
+	if c.KodiURL != "" {
+		ns = append(ns, &kodiNotifier{client: client, url: c.KodiURL})
+	}
+	return ns, nil
+}
+
+//pathMap translates a local path prefix into
+//the path used by a media server
+type pathMap struct {
+	from, to string
+}
+
+//parsePathMap parses comma separated from=to pairs
+func parsePathMap(s string) ([]pathMap, error) {
+	paths := []pathMap{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("Invalid path map '%s' (expected from=to)", pair)
+		}
+		paths = append(paths, pathMap{from: filepath.Clean(abs(kv[0])), to: kv[1]})
+	}
+	return paths, nil
+}
+
+//mapPath translates dir using the longest matching prefix,
+//the remainder uses the separators of the server path
+func mapPath(dir string, paths []pathMap) string {
+	match := -1
+	for i, p := range paths {
+		if inside(dir, p.from) && (match == -1 || len(p.from) > len(paths[match].from)) {
+			match = i
+		}
+	}
+	if match == -1 {
+		return dir
+	}
+	p := paths[match]
+	rest := filepath.ToSlash(strings.TrimPrefix(dir, p.from))
+	if strings.Contains(p.to, `\`) {
+		return strings.TrimSuffix(p.to, `\`) + strings.Replace(rest, "/", `\`, -1)
+	}
+	return strings.TrimSuffix(p.to, "/") + rest
+}
+
+//serverInside reports whether path is dir or inside it, using the
+//separators of the server (which may differ from this host's)
+func serverInside(path, dir string) bool {
+	path = strings.Replace(path, `\`, "/", -1)
+	dir = strings.TrimSuffix(strings.Replace(dir, `\`, "/", -1), "/")
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+//notify batches the directories of the run's sorted
+//files into one notification per media server
+func (fs *fsSort) notify() {
+	if len(fs.notifiers) == 0 || fs.DryRun {
+		return
+	}
+	set := map[string]bool{}
+	for _, file := range fs.sorts {
+		if file.status() == SortedStatus {
+			set[filepath.Dir(abs(file.dest))] = true
+		}
+	}
+	if len(set) == 0 {
+		return
+	}
+	dirs := []string{}
+	for dir := range set {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, n := range fs.notifiers {
+		if err := n.notify(dirs); err != nil {
+			fs.hookFailed("notify "+n.name(), "", err)
+			continue
+		}
+		log.Printf("Notified %s of %d directories", color.CyanString(n.name()), len(dirs))
+	}
+}
+
+//request performs an http request, failing on non-2xx responses
+func request(client *http.Client, method, url string, body io.Reader, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	return b, nil
+}
+
+//plexNotifier requests a partial scan of each directory
+//from the library section which contains it
+type plexNotifier struct {
+	client     *http.Client
+	url, token string
+	//paths translate local directories into plex locations
+	paths []pathMap
+}
+
+type plexSections struct {
+	Directories []struct {
+		Key       string `xml:"key,attr"`
+		Locations []struct {
+			Path string `xml:"path,attr"`
+		} `xml:"Location"`
+	} `xml:"Directory"`
+}
+
+func (p *plexNotifier) name() string {
+	return "plex"
+}
+
+func (p *plexNotifier) endpoint(path string, v url.Values) string {
+	v.Set("X-Plex-Token", p.token)
+	return strings.TrimSuffix(p.url, "/") + path + "?" + v.Encode()
+}
+
+func (p *plexNotifier) notify(dirs []string) error {
+	b, err := request(p.client, "GET", p.endpoint("/library/sections", url.Values{}), nil, nil)
+	if err != nil {
+		return err
+	}
+	sections := plexSections{}
+	if err := xml.Unmarshal(b, &sections); err != nil {
+		return fmt.Errorf("Invalid plex sections: %s", err)
+	}
+	missing, failed := []string{}, []string{}
+	for _, dir := range dirs {
+		dir = mapPath(dir, p.paths)
+		//find the section with the longest location containing dir
+		key, longest := "", 0
+		for _, s := range sections.Directories {
+			for _, l := range s.Locations {
+				if serverInside(dir, l.Path) && len(l.Path) > longest {
+					key, longest = s.Key, len(l.Path)
+				}
+			}
+		}
+		if key == "" {
+			missing = append(missing, dir)
+			continue
+		}
+		v := url.Values{}
+		v.Set("path", dir)
+		//keep refreshing the other directories
+		if _, err := request(p.client, "GET", p.endpoint("/library/sections/"+key+"/refresh", v), nil, nil); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", dir, err))
+		}
+	}
+	errs := []string{}
+	if len(missing) > 0 {
+		errs = append(errs, "No plex library contains: "+strings.Join(missing, ", "))
+	}
+	if len(failed) > 0 {
+		errs = append(errs, "Failed to refresh: "+strings.Join(failed, ", "))
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+//jellyfinNotifier refreshes all libraries of a jellyfin
+//or emby server, which share the same api
+type jellyfinNotifier struct {
+	client     *http.Client
+	url, token string
+}
+
+func (j *jellyfinNotifier) name() string {
+	return "jellyfin"
+}
+
+func (j *jellyfinNotifier) notify(dirs []string) error {
+	header := http.Header{}
+	header.Set("X-Emby-Token", j.token)
+	_, err := request(j.client, "POST", strings.TrimSuffix(j.url, "/")+"/Library/Refresh", nil, header)
+	return err
+}
+
+//kodiNotifier starts a video library scan using json-rpc,
+//credentials may be included in the url
+type kodiNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func (k *kodiNotifier) name() string {
+	return "kodi"
+}
+
+func (k *kodiNotifier) notify(dirs []string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "VideoLibrary.Scan",
+	})
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	b, err := request(k.client, "POST", k.url, bytes.NewReader(body), header)
+	if err != nil {
+		return err
+	}
+	resp := struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return fmt.Errorf("Invalid kodi response: %s", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("Kodi error: %s", resp.Error.Message)
+	}
+	return nil
+}
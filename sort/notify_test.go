@@ -0,0 +1,126 @@
+package mediasort
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotify(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("path")+string(b))
+		switch r.URL.Path {
+		case "/library/sections":
+			if r.URL.Query().Get("X-Plex-Token") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `<MediaContainer>
+				<Directory key="1"><Location path="/media"/></Directory>
+				<Directory key="2"><Location path="/media/tv"/></Directory>
+				<Directory key="3"><Location path="/media/broken"/></Directory>
+			</MediaContainer>`)
+		case "/library/sections/3/refresh":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/Library/Refresh":
+			if r.Header.Get("X-Emby-Token") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		case "/jsonrpc":
+			fmt.Fprint(w, `{"id":1,"jsonrpc":"2.0","result":"OK"}`)
+		}
+	}))
+	defer server.Close()
+	//local paths are mapped to the plex server's paths
+	root := abs(filepath.FromSlash("/srv/media"))
+	fs := testSort()
+	fs.PlexURL, fs.PlexToken = server.URL, "secret"
+	fs.PlexPathMap = root + "=/media"
+	fs.JellyfinURL, fs.JellyfinToken = server.URL, "secret"
+	fs.KodiURL = server.URL + "/jsonrpc"
+	notifiers, err := fs.Config.notifiers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.notifiers = notifiers
+	fs.sorts = map[string]*fileSort{
+		"a": {dest: filepath.Join(root, "tv", "Dark", "Dark S01E01.mkv")},
+		"b": {dest: filepath.Join(root, "tv", "Dark", "Dark S01E02.mkv")},
+		"c": {dest: filepath.Join(root, "movies", "Heat (1995).mkv")},
+		"d": {},
+	}
+	fs.notify()
+	if len(fs.hookErrors) > 0 {
+		t.Fatal(fs.hookErrors)
+	}
+	//directories are batched
+	expected := []string{
+		"GET /library/sections ",
+		"GET /library/sections/1/refresh /media/movies",
+		"GET /library/sections/2/refresh /media/tv/Dark",
+		"POST /Library/Refresh ",
+		`POST /jsonrpc {"id":1,"jsonrpc":"2.0","method":"VideoLibrary.Scan"}`,
+	}
+	if got := strings.Join(requests, "\n"); got != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected requests:\n%s", got)
+	}
+	//failures are reported in the summary
+	fs.notifiers = []notifier{&plexNotifier{client: http.DefaultClient, url: server.URL, token: "wrong"}}
+	fs.notify()
+	if len(fs.hookErrors) != 1 || !strings.Contains(fs.hookErrors[0], "401") {
+		t.Fatalf("unexpected errors: %v", fs.hookErrors)
+	}
+	//a failed refresh does not stop the others
+	requests, fs.hookErrors = nil, nil
+	fs.notifiers = notifiers[:1]
+	fs.sorts["d"] = &fileSort{dest: filepath.Join(root, "broken", "Movie (2010).mkv")}
+	fs.notify()
+	if len(fs.hookErrors) != 1 || !strings.Contains(fs.hookErrors[0], "500") {
+		t.Fatalf("unexpected errors: %v", fs.hookErrors)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("expected all directories to be refreshed:\n%s", strings.Join(requests, "\n"))
+	}
+}
+
+func TestPathMap(t *testing.T) {
+	if _, err := parsePathMap("/media"); err == nil {
+		t.Fatal("expected invalid path map")
+	}
+	root := abs(filepath.FromSlash("/srv"))
+	paths, err := parsePathMap(root + "=/data," + filepath.Join(root, "tv") + `=T:\tv`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for dir, expect := range map[string]string{
+		filepath.Join(root, "movies", "Heat (1995)"): "/data/movies/Heat (1995)",
+		filepath.Join(root, "tv", "Dark"):            `T:\tv\Dark`,
+		abs(filepath.FromSlash("/other")):            abs(filepath.FromSlash("/other")),
+	} {
+		if got := mapPath(dir, paths); got != expect {
+			t.Fatalf("%s: expected %s, got %s", dir, expect, got)
+		}
+	}
+	//mapped paths are compared with the server's separators
+	for _, tc := range []struct {
+		Path, Dir string
+		Inside    bool
+	}{
+		{`T:\tv\Dark`, `T:\tv`, true},
+		{`T:\tv\Dark`, `T:\tv\`, true},
+		{`T:\tv2\Dark`, `T:\tv`, false},
+		{"/data/movies/Heat (1995)", "/data/", true},
+		{"/data/movies", "/data/movies", true},
+		{"/database", "/data", false},
+	} {
+		if serverInside(tc.Path, tc.Dir) != tc.Inside {
+			t.Fatalf("%s in %s: expected %v", tc.Path, tc.Dir, tc.Inside)
+		}
+	}
+}
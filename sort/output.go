@@ -0,0 +1,84 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+//Output is a machine-readable format of the run
+//report, written to stdout while logs go to stderr
+type Output string
+
+const (
+	//JSONOutput writes a single report of the run,
+	//which is not supported when watching
+	JSONOutput Output = "json"
+	//NDJSONOutput writes a line for each file as it is
+	//sorted, followed by a line with the run summary
+	NDJSONOutput Output = "ndjson"
+)
+
+func (o Output) validate(watch bool) error {
+	switch o {
+	case JSONOutput:
+		if watch {
+			//each run would write another report
+			return errors.New("Output json is a single report, use --output ndjson with --watch")
+		}
+		return nil
+	case "", NDJSONOutput:
+		return nil
+	}
+	return fmt.Errorf("Unknown output: %s", o)
+}
+
+//write encodes v as a line of output
+func (fs *fsSort) write(v interface{}, indent bool) {
+	var b []byte
+	var err error
+	if indent {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		fs.verbf("failed to encode output: %s", err)
+		return
+	}
+	fs.outputLock.Lock()
+	fs.output.Write(append(b, '\n'))
+	fs.outputLock.Unlock()
+}
+
+//outputFile writes the outcome of a file
+func (fs *fsSort) outputFile(file *fileSort) {
+	if fs.Output == NDJSONOutput {
+		fs.write(fs.entry(file), false)
+	}
+}
+
+//outputRun writes the outcome of the run,
+//including the error which aborted it
+func (fs *fsSort) outputRun(err error) {
+	r := fs.report()
+	if err != nil {
+		r.Summary.Error = err.Error()
+	}
+	switch fs.Output {
+	case JSONOutput:
+		fs.write(r, true)
+	case NDJSONOutput:
+		fs.write(struct {
+			Summary Summary `json:"summary"`
+		}{r.Summary}, false)
+	}
+}
+
+//abort writes the summary of a run which
+//failed before finishing, and returns err
+func (fs *fsSort) abort(err error) error {
+	fs.outputRun(err)
+	fs.closeJournal()
+	return err
+}
@@ -0,0 +1,83 @@
+package mediasort
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	fs := testSort()
+	out := &bytes.Buffer{}
+	fs.output = out
+	fs.Output = NDJSONOutput
+	fs.sorts = map[string]*fileSort{
+		"Dark.2017.S01E02.mkv": {
+			path:   "Dark.2017.S01E02.mkv",
+			dest:   "tv/Dark S01E02.mkv",
+			used:   step{action: MoveAction},
+			result: &Result{Name: "Dark", Year: "2017", MType: "series", Accuracy: 100, Season: 1, Episode: 2},
+		},
+		"Moive.mkv": {path: "Moive.mkv", err: errors.New("No results")},
+	}
+	for _, file := range fs.sorts {
+		fs.outputFile(file)
+	}
+	fs.outputRun(nil)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %s", out)
+	}
+	entries := map[string]Entry{}
+	for _, line := range lines[:2] {
+		e := Entry{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries[e.Source] = e
+	}
+	e := entries["Dark.2017.S01E02.mkv"]
+	if e.Status != SortedStatus || e.Action != "move" || e.Match == nil || e.Match.Accuracy != 100 ||
+		e.Parsed == nil || e.Parsed.Query != "dark" || e.Parsed.Season != 1 || e.Parsed.Episode != 2 {
+		t.Fatalf("unexpected entry: %s", lines)
+	}
+	if e := entries["Moive.mkv"]; e.Status != FailedStatus || e.Error != "No results" || e.Match != nil {
+		t.Fatalf("unexpected entry: %s", lines)
+	}
+	if lines[2] != `{"summary":{"sorted":1,"skipped":0,"failed":1}}` {
+		t.Fatalf("unexpected summary: %s", lines[2])
+	}
+	//json is a single report
+	out.Reset()
+	fs.Output = JSONOutput
+	fs.outputFile(fs.sorts["Moive.mkv"])
+	fs.outputRun(nil)
+	r := Report{}
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Summary.Sorted != 1 || len(r.Sorted) != 1 || len(r.Failed) != 1 || r.Sorted[0].Destination != "tv/Dark S01E02.mkv" {
+		t.Fatalf("unexpected report: %s", out)
+	}
+	//aborted runs are still summarized
+	out.Reset()
+	fs = testSort()
+	fs.output = out
+	fs.Output = NDJSONOutput
+	fs.Targets = []string{"does-not-exist"}
+	if err := fs.run(); err == nil {
+		t.Fatal("expected an error")
+	}
+	s := struct {
+		Summary Summary `json:"summary"`
+	}{}
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil || s.Summary.Error == "" {
+		t.Fatalf("unexpected summary: %s", out)
+	}
+	//a single report cannot be watched
+	if err := JSONOutput.validate(true); err == nil {
+		t.Fatal("expected json with watch to be rejected")
+	}
+}
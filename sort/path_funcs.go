@@ -0,0 +1,101 @@
+package mediasort
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+//prettyPathFuncs are the functions available to path templates,
+//string arguments come last so they can be used in pipelines:
+//  {{ .Name | replace "&" "and" | slug }}
+var prettyPathFuncs = template.FuncMap{
+	"pad":       pad,
+	"sortTitle": sortTitle,
+	"initial":   initial,
+	"title":     strings.Title,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"replace":   replace,
+	"ascii":     ascii,
+	"slug":      slug,
+	"extraDir":  extraDir,
+}
+
+//RegisterTemplateFuncs adds the provided functions to those
+//available in the TV and movie path templates, existing functions
+//with the same name are replaced. It should be called before
+//any sorting begins.
+func RegisterTemplateFuncs(funcs template.FuncMap) {
+	for name, fn := range funcs {
+		prettyPathFuncs[name] = fn
+	}
+}
+
+var (
+	articles = regexp.MustCompile(`(?i)^(the|a|an) (.+)$`)
+	nonslug  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+//pad zero-pads n to the given width
+func pad(width, n int) string {
+	return fmt.Sprintf("%0*d", width, n)
+}
+
+//sortTitle moves a leading article to the end,
+//"The Office" becomes "Office, The"
+func sortTitle(s string) string {
+	m := articles.FindStringSubmatch(s)
+	if len(m) == 0 {
+		return s
+	}
+	return m[2] + ", " + m[1]
+}
+
+//initial returns the upper-cased first letter of s,
+//ignoring leading articles, or "#" when s begins with a
+//number or symbol
+func initial(s string) string {
+	if m := articles.FindStringSubmatch(s); len(m) > 0 {
+		s = m[2]
+	}
+	for _, r := range ascii(s) {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+		if !unicode.IsSpace(r) {
+			break
+		}
+	}
+	return "#"
+}
+
+//replace all occurrences of old with new in s
+func replace(old, new, s string) string {
+	return strings.Replace(s, old, new, -1)
+}
+
+//ascii folds s into ascii, dropping any characters
+//which could not be transliterated
+func ascii(s string) string {
+	s = mediasearch.Fold(s)
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+//slug converts s into a lower-case, dash-separated,
+//ascii string: "Amélie (2001)" becomes "amelie-2001"
+func slug(s string) string {
+	s = strings.ToLower(ascii(s))
+	s = nonslug.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -46,6 +47,10 @@ type Result struct {
 	EpisodeDate                   string //weekly series
 	Year                          string
 	Accuracy                      int
+	Extra                         string //trailer, featurette, etc
+	IMDBID                        string
+	TMDBID, TVDBID                int
+	OriginalTitle, Plot           string
 }
 
 var (
@@ -53,46 +58,92 @@ var (
 	DefaultTVTemplate = `{{ .Name }} S{{ printf "%02d" .Season }}E{{ printf "%02d" .Episode }}` +
 		`{{ if ne .ExtraEpisode -1 }}-{{ printf "%02d" .ExtraEpisode }}{{end}}.{{ .Ext }}`
 	//DefaultMovieTemplate defines the default movie path format
-	DefaultMovieTemplate = "{{ .Name }} ({{ .Year }}).{{ .Ext }}"
+	DefaultMovieTemplate = "{{ .Name }} ({{ .Year }}){{ with .Extra }}-{{ . }}{{ end }}.{{ .Ext }}"
 )
 
 //PathConfig customises the path templates
 type PathConfig struct {
-	TVTemplate    string `help:"tv series path template"`
-	MovieTemplate string `help:"movie path template"`
+	Layout        Layout   `help:"preset path templates for a media server (plex|jellyfin|emby|kodi|flat)"`
+	TVTemplate    string   `help:"tv series path template (overrides layout)"`
+	MovieTemplate string   `help:"movie path template (overrides layout)"`
+	Sanitize      Sanitize `help:"file name sanitization profile, windows is also safe for SMB shares (posix|windows|ascii)"`
+	MaxNameLength int      `help:"maximum length in bytes of each file and folder name (0 is unlimited)"`
 }
 
-var prettyPathFuncs = template.FuncMap{}
-
 //PrettyPath converts the provided "messy" path into a
 //"pretty" cleanly formatted path using the media result
 func (result *Result) PrettyPath(config PathConfig) (string, error) {
-	//config
-	if config.TVTemplate == "" {
-		config.TVTemplate = DefaultTVTemplate
+	t, err := config.compile()
+	if err != nil {
+		return "", err
 	}
-	if config.MovieTemplate == "" {
-		config.MovieTemplate = DefaultMovieTemplate
+	return t.prettyPath(result)
+}
+
+//pathTemplates are the compiled tv and movie path templates
+type pathTemplates struct {
+	tv, movie     *template.Template
+	sanitize      Sanitize
+	maxNameLength int
+}
+
+//sample results used to validate path templates
+var (
+	sampleSeries = Result{Query: "the office", Name: "The Office", Path: "the.office.s02e03.mkv",
+		Ext: "mkv", MType: string(mediasearch.Series), Season: 2, Episode: 3, ExtraEpisode: -1,
+		Year: "2005", Accuracy: 100, IMDBID: "tt0386676", TMDBID: 2316, TVDBID: 73244}
+	sampleMovie = Result{Query: "inception", Name: "Inception", Path: "inception.2010.mkv",
+		Ext: "mkv", MType: string(mediasearch.Movie), Season: 1, Episode: -1, ExtraEpisode: -1,
+		Year: "2010", Accuracy: 100, IMDBID: "tt1375666", TMDBID: 27205}
+)
+
+//compile parses both templates once, and validates them
+//by rendering the sample tv series and movie results
+func (config PathConfig) compile() (*pathTemplates, error) {
+	config, err := config.withLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Sanitize.validate(); err != nil {
+		return nil, err
+	}
+	t := &pathTemplates{sanitize: config.Sanitize, maxNameLength: config.MaxNameLength}
+	if t.tv, err = template.New("tv").Funcs(prettyPathFuncs).Parse(config.TVTemplate); err != nil {
+		return nil, fmt.Errorf("Invalid TV template: %s", err)
+	}
+	if t.movie, err = template.New("movie").Funcs(prettyPathFuncs).Parse(config.MovieTemplate); err != nil {
+		return nil, fmt.Errorf("Invalid movie template: %s", err)
+	}
+	for _, r := range []Result{sampleSeries, sampleMovie} {
+		p, err := t.prettyPath(&r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s template: %s", r.MType, err)
+		}
+		if p == "" || strings.HasSuffix(p, sep) || strings.HasSuffix(p, "/") {
+			return nil, fmt.Errorf("Invalid %s template: renders an empty file name", r.MType)
+		}
 	}
+	return t, nil
+}
+
+//prettyPath renders the result with its template
+func (t *pathTemplates) prettyPath(result *Result) (string, error) {
 	//find template
-	tmpl := ""
+	var tmpl *template.Template
 	switch mediasearch.MediaType(result.MType) {
 	case mediasearch.Series:
-		tmpl = config.TVTemplate
+		tmpl = t.tv
 	case mediasearch.Movie:
-		tmpl = config.MovieTemplate
+		tmpl = t.movie
 	default:
 		return "", fmt.Errorf("Invalid result type: %s", result.MType)
 	}
 	//run template
 	str := bytes.Buffer{}
-	if t, err := template.New("t").Funcs(prettyPathFuncs).Parse(tmpl); err != nil {
-		return "", err
-	} else if err := t.Execute(&str, result); err != nil {
+	if err := tmpl.Execute(&str, result); err != nil {
 		return "", err
 	}
-
-	prettyPath := fixPath(str.String())
+	prettyPath := sanitizePath(str.String(), t.sanitize, t.maxNameLength)
 	return prettyPath, nil
 }
 
@@ -109,6 +160,11 @@ func runPathParse(path string, depth int) (Result, error) {
 	dir, name := filepath.Split(path)
 	ext := getExtension(name)
 	name = strings.TrimSuffix(name, ext)
+	//extract extras suffix (movie-trailer)
+	if m := extra.FindStringSubmatch(name); len(m) > 0 {
+		name = strings.TrimSuffix(name, m[0])
+		result.Extra = strings.ToLower(m[1])
+	}
 	//add depth*parts of dir onto name
 	dir = strings.Trim(dir, sep)
 	parts := []string{}
@@ -203,17 +259,50 @@ func runPathParse(path string, depth int) (Result, error) {
 	return result, nil
 }
 
+//searchThreshold searches for the best match (replaced in tests)
+var searchThreshold = mediasearch.SearchThreshold
+
 func runPathSort(path string, threshold, depth int) (Result, error) {
 	result, err := runPathParse(path, depth)
 	if err != nil {
 		return result, err
 	}
+	if ok, err := result.overridePath(); ok || err != nil {
+		return result, err
+	}
 	//search for normalized name
-	searchResult, err := mediasearch.SearchThreshold(result.Query, result.Year, result.MType, threshold)
+	searchResult, err := searchThreshold(result.Query, result.Year, result.MType, threshold)
 	if err != nil {
 		return result, err
 	}
 	//use results
+	result.apply(searchResult)
+	return result, nil
+}
+
+//overridePath applies the override of the
+//path or its directories, when there is one
+func (result *Result) overridePath() (bool, error) {
+	searchResult, ok, err := mediasearch.PathOverride(result.Path, result.MType)
+	if ok && err == nil {
+		result.apply(searchResult)
+	}
+	return ok, err
+}
+
+//DefaultOverridesFile is the default location of the overrides file,
+//which is empty (no overrides are loaded or saved) when there is
+//no user config directory
+func DefaultOverridesFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "media-sort", "overrides.json")
+}
+
+//apply copies the search result into the result
+func (result *Result) apply(searchResult mediasearch.Result) {
 	result.Name = searchResult.Title
 	if searchResult.Type == mediasearch.Series && searchResult.IsDupe { //differentiate duplicates by year
 		result.Name += " (" + searchResult.Year + ")"
@@ -221,5 +310,9 @@ func runPathSort(path string, threshold, depth int) (Result, error) {
 	result.Year = searchResult.Year
 	result.MType = string(searchResult.Type)
 	result.Accuracy = searchResult.Accuracy
-	return result, nil
+	result.IMDBID = searchResult.IMDBID
+	result.TMDBID = searchResult.TMDBID
+	result.TVDBID = searchResult.TVDBID
+	result.OriginalTitle = searchResult.OriginalTitle
+	result.Plot = searchResult.Plot
 }
@@ -82,6 +82,18 @@ func TestPathParse(t *testing.T) {
 				Year:  "2012",
 			},
 		},
+		{
+			"/movies/Inception (2010)-Trailer.mkv",
+			0,
+			Result{
+				Query: "inception",
+				Name:  "Inception (2010)",
+				Ext:   "mkv",
+				MType: string(mediasearch.Movie),
+				Year:  "2010",
+				Extra: "trailer",
+			},
+		},
 		{
 			"/my/movie/xyz 2012.mp4",
 			-1, //all dirs
@@ -119,3 +131,152 @@ func TestPathParse(t *testing.T) {
 		}
 	}
 }
+
+func TestPrettyPathFuncs(t *testing.T) {
+	RegisterTemplateFuncs(map[string]interface{}{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	})
+	for _, tc := range []struct {
+		Template string
+		Result   Result
+		Expect   string
+	}{
+		{
+			`{{ initial .Name }}/{{ .Name }} ({{ .Year }}).{{ .Ext }}`,
+			Result{Name: "The Matrix", Year: "1999", Ext: "mkv"},
+			"M/The Matrix (1999).mkv",
+		},
+		{
+			`{{ sortTitle .Name }} {{ pad 3 .Season }}.{{ .Ext }}`,
+			Result{Name: "The Office", Season: 2, Ext: "mkv"},
+			"Office, The 002.mkv",
+		},
+		{
+			`{{ initial .Name }}/{{ .Name | ascii | replace "e" "3" }}.{{ .Ext }}`,
+			Result{Name: "12 Amélies", Ext: "mp4"},
+			"#/12 Am3li3s.mp4",
+		},
+		{
+			`{{ slug .Name }}-{{ .Year }}.{{ .Ext }}`,
+			Result{Name: "Léon: The Professional", Year: "1994", Ext: "mp4"},
+			"leon-the-professional-1994.mp4",
+		},
+		{
+			`{{ .Name | lower | title | shout }}.{{ .Ext }}`,
+			Result{Name: "THE wire", Ext: "mp4"},
+			"THE WIRE!.mp4",
+		},
+	} {
+		r := tc.Result
+		r.MType = string(mediasearch.Movie)
+		got, err := r.PrettyPath(PathConfig{MovieTemplate: tc.Template})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = strings.ReplaceAll(got, sep, "/")
+		if got != tc.Expect {
+			t.Errorf("template: %s\ngot: %s\nexp: %s", tc.Template, got, tc.Expect)
+		}
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	show := Result{Name: "The Office", MType: string(mediasearch.Series), Season: 2, Episode: 3, ExtraEpisode: 4, Ext: "mkv", TVDBID: 73244}
+	movie := Result{Name: "Inception", MType: string(mediasearch.Movie), Year: "2010", Ext: "mkv", TMDBID: 27205, IMDBID: "tt1375666"}
+	trailer := movie
+	trailer.Extra = "trailer"
+	for _, tc := range []struct {
+		Layout                    Layout
+		Show, Movie, MovieTrailer string
+	}{
+		{
+			FlatLayout,
+			"The Office S02E03-04.mkv",
+			"Inception (2010).mkv",
+			"Inception (2010)-trailer.mkv",
+		},
+		{
+			PlexLayout,
+			"The Office {tvdb-73244}/Season 02/The Office - S02E03-E04.mkv",
+			"Inception (2010) {tmdb-27205}/Inception (2010).mkv",
+			"Inception (2010) {tmdb-27205}/Trailers/Inception (2010)-trailer.mkv",
+		},
+		{
+			JellyfinLayout,
+			"The Office [tvdbid-73244]/Season 02/The Office - S02E03-E04.mkv",
+			"Inception (2010) [tmdbid-27205]/Inception (2010).mkv",
+			"Inception (2010) [tmdbid-27205]/Trailers/Inception (2010)-trailer.mkv",
+		},
+		{
+			EmbyLayout,
+			"The Office [tvdbid=73244]/Season 02/The Office - S02E03-E04.mkv",
+			"Inception (2010) [tmdbid=27205]/Inception (2010).mkv",
+			"Inception (2010) [tmdbid=27205]/Trailers/Inception (2010)-trailer.mkv",
+		},
+		{
+			KodiLayout,
+			"The Office/Season 02/The Office - S02E03E04.mkv",
+			"Inception (2010)/Inception (2010).mkv",
+			"Inception (2010)/Inception (2010)-trailer.mkv",
+		},
+	} {
+		c := PathConfig{Layout: tc.Layout}
+		for r, exp := range map[*Result]string{&show: tc.Show, &movie: tc.Movie, &trailer: tc.MovieTrailer} {
+			got, err := r.PrettyPath(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = strings.ReplaceAll(got, sep, "/")
+			if got != exp {
+				t.Errorf("layout %s\ngot: %s\nexp: %s", tc.Layout, got, exp)
+			}
+		}
+	}
+	if _, err := show.PrettyPath(PathConfig{Layout: "nope"}); err == nil {
+		t.Fatal("expected unknown layout error")
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	for _, c := range []PathConfig{
+		{TVTemplate: "{{ .Name "},
+		{MovieTemplate: "{{ .Title }}.{{ .Ext }}"},
+		{MovieTemplate: "{{ unknownFunc .Name }}"},
+		{TVTemplate: "{{ .Name }}/"},
+	} {
+		if _, err := c.compile(); err == nil {
+			t.Errorf("expected %+v to be invalid", c)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	long := strings.Repeat("Long Title ", 10)
+	for _, tc := range []struct {
+		Input   string
+		Profile Sanitize
+		Max     int
+		Expect  string
+	}{
+		{`What If...?/Who's "Afraid"*.mkv`, PosixSanitize, 0, `What If...?/Whos "Afraid"*.mkv`},
+		{`What If...?/Who's "Afraid"*.mkv`, WindowsSanitize, 0, `What If/Whos Afraid-.mkv`},
+		{`Con/Amélie: Part 1.mkv`, WindowsSanitize, 0, `_Con/Amélie - Part 1.mkv`},
+		{`Amélie/Ærø Москва.mkv`, ASCIISanitize, 0, `Amelie/Aero Moskva.mkv`},
+		{long + "S01E02-E03 - An Episode Title.mkv", PosixSanitize, 40, "Long Title Long Title S01E02-E03.mkv"},
+		{"Long Title S01E02 - An Episode Title.mkv", PosixSanitize, 30, "Long Title S01E02 - An.mkv"},
+		{long + "S01E02.mkv", PosixSanitize, 20, "Long S01E02.mkv"},
+		{long + "(2010) {tmdb-123}/x.mkv", PosixSanitize, 40, "Long Title Long Title (2010) {tmdb-123}/x.mkv"},
+		{"Amélie Amélie.mkv", PosixSanitize, 12, "Amélie.mkv"},
+	} {
+		got := sanitizePath(tc.Input, tc.Profile, tc.Max)
+		got = strings.ReplaceAll(got, sep, "/")
+		if got != tc.Expect {
+			t.Errorf("sanitize %s (%s, %d)\ngot: %s\nexp: %s", tc.Input, tc.Profile, tc.Max, got, tc.Expect)
+		}
+		for _, part := range strings.Split(got, "/") {
+			if tc.Max > 0 && len(part) > tc.Max {
+				t.Errorf("sanitize %s: %s is longer than %d", tc.Input, part, tc.Max)
+			}
+		}
+	}
+}
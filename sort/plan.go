@@ -0,0 +1,390 @@
+package mediasort
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+//Plan is the resolved operations of a sort,
+//which media-sort apply performs exactly
+type Plan struct {
+	Created time.Time `json:"created"`
+	//Config is the configuration of the sort,
+	//which is also used to apply the plan
+	Config     Config             `json:"config"`
+	Operations []PlannedOperation `json:"operations"`
+}
+
+//PlannedOperation sorts a file and its companions
+type PlannedOperation struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	//Overwrite the existing destination
+	Overwrite bool `json:"overwrite,omitempty"`
+	//Size, ModTime and Hash identify the source, which
+	//must not have changed when the plan is applied
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+	//Existing identifies the overwritten destination,
+	//which must not have changed when the plan is applied
+	Existing   *Fingerprint       `json:"existing,omitempty"`
+	Companions []PlannedCompanion `json:"companions,omitempty"`
+	Result     *Result            `json:"result"`
+}
+
+//Fingerprint identifies the content of a file
+type Fingerprint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+//fingerprint the file at path
+func fingerprint(path string, info os.FileInfo) (Fingerprint, error) {
+	hash, err := partialHash(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Size: info.Size(), ModTime: info.ModTime(), Hash: hex.EncodeToString(hash)}, nil
+}
+
+//matches returns whether the file at path still has this fingerprint
+func (f Fingerprint) matches(path string) (os.FileInfo, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Size() != f.Size || !info.ModTime().Equal(f.ModTime) {
+		return info, false, nil
+	}
+	fp, err := fingerprint(path, info)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, fp.Hash == f.Hash, nil
+}
+
+//PlannedCompanion sorts a subtitle, nfo or artwork file
+type PlannedCompanion struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+//PlanConfig is the configuration of media-sort plan
+type PlanConfig struct {
+	Config
+	PlanFile string `opts:"help=file which the plan is written to"`
+}
+
+//ApplyConfig is the configuration of media-sort apply
+type ApplyConfig struct {
+	PlanFile string `opts:"mode=arg,name=plan"`
+	DryRun   bool   `opts:"help=check the plan without changing any files"`
+}
+
+//MakePlan searches for and resolves the operations which would sort
+//the targets, without changing any files, and writes them to a plan
+func MakePlan(c PlanConfig) error {
+	if c.PlanFile == "" {
+		return errors.New("Plan file is required")
+	}
+	switch {
+	case c.Watch:
+		return errors.New("Plans do not support --watch")
+	case c.NFO:
+		return errors.New("Plans do not support --nfo")
+	case c.UnmatchedDir != "" || c.ReviewDir != "":
+		return errors.New("Plans do not support --unmatched-dir or --review-dir")
+	case c.OnConflict == QuarantineConflict:
+		return errors.New("Plans do not support --on-conflict quarantine")
+	case c.Dupes == DeleteDupes || c.Dupes == LinkDupes:
+		return errors.New("Plans only support --dupes skip")
+	}
+	//the plan may be applied from another directory
+	for i, t := range c.Targets {
+		c.Targets[i] = abs(t)
+	}
+	for _, dir := range []*string{&c.TVDir, &c.MovieDir, &c.QuarantineDir} {
+		if *dir != "" {
+			*dir = abs(*dir)
+		}
+	}
+	if c.TVDir == "" {
+		c.TVDir = abs(".")
+	}
+	if c.MovieDir == "" {
+		c.MovieDir = abs(".")
+	}
+	config := c.Config
+	config.DryRun = true
+	fs, err := newFSSort(config)
+	if err != nil {
+		return err
+	}
+	plan := &Plan{Created: time.Now(), Config: c.Config, Operations: []PlannedOperation{}}
+	fs.plan = plan
+	fs.planned = map[string]int{}
+	if err := fs.run(); err != nil {
+		return err
+	}
+	sort.Slice(plan.Operations, func(i, j int) bool {
+		return plan.Operations[i].Source < plan.Operations[j].Source
+	})
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	//the config may contain tokens
+	if err := ioutil.WriteFile(c.PlanFile, append(b, '\n'), 0600); err != nil {
+		return err
+	}
+	log.Printf("Planned %d operations, apply with: media-sort apply %s", len(plan.Operations), c.PlanFile)
+	return nil
+}
+
+//planFile adds the file's operation to the plan
+func (fs *fsSort) planFile(file *fileSort, newPath string, overwrote os.FileInfo, companions []*companion) error {
+	source, err := fingerprint(file.path, file.info)
+	if err != nil {
+		return err
+	}
+	op := PlannedOperation{
+		Source:  abs(file.path),
+		Size:    source.Size,
+		ModTime: source.ModTime,
+		Hash:    source.Hash,
+		Result:  file.result,
+	}
+	if overwrote != nil {
+		existing, err := fingerprint(newPath, overwrote)
+		if err != nil {
+			return err
+		}
+		op.Overwrite, op.Existing = true, &existing
+	}
+	fs.planLock.Lock()
+	defer fs.planLock.Unlock()
+	//another planned file may be sorted here first
+	replaces := -1
+	if i, ok := fs.planned[abs(newPath)]; ok {
+		path, replace, err := fs.planConflict(file, newPath, fs.plan.Operations[i])
+		if err != nil || path == "" {
+			return err
+		}
+		if replace {
+			replaces = i
+		} else {
+			op.Overwrite, op.Existing = false, nil
+		}
+		if path != newPath {
+			log.Printf("[#%d/%d] destination is already planned\n  └─> %s", file.id, len(fs.sorts), color.YellowString(path))
+		}
+		newPath = path
+	}
+	op.Destination = abs(newPath)
+	dsts := companionPaths(companions, newPath)
+	for i, c := range companions {
+		op.Companions = append(op.Companions, PlannedCompanion{Source: abs(c.path), Destination: abs(dsts[i])})
+	}
+	if replaces >= 0 {
+		//the replaced file is left where it is
+		replaced := fs.sorts[fs.plan.Operations[replaces].Source]
+		if replaced != nil {
+			replaced.dest, replaced.reason = "", "Replaced by "+file.path
+		}
+		fs.plan.Operations[replaces] = op
+	} else {
+		fs.planned[op.Destination] = len(fs.plan.Operations)
+		fs.plan.Operations = append(fs.plan.Operations, op)
+	}
+	file.dest = newPath
+	return nil
+}
+
+//planConflict resolves a destination which is already planned,
+//as a run would once the planned file had been sorted there. It
+//returns the new destination, empty when the file is skipped, and
+//whether the planned operation is replaced.
+func (fs *fsSort) planConflict(file *fileSort, newPath string, planned PlannedOperation) (string, bool, error) {
+	prefix := fmt.Sprintf("[#%d/%d]", file.id, len(fs.sorts))
+	reason := "destination is already planned"
+	if fs.Overwrite || (fs.OverwriteIfLarger && file.info.Size() > planned.Size) {
+		log.Printf("%s Replacing planned %s", prefix, planned.Source)
+		return newPath, true, nil
+	}
+	if fs.OverwriteIfBetter {
+		//the planned source still has its quality tags
+		decision, quality, r := fs.compareQuality(file.path, planned.Source)
+		switch decision {
+		case replaceDuplicate:
+			log.Printf("%s Replacing planned duplicate, %s", prefix, r)
+			return newPath, true, nil
+		case keepBoth:
+			log.Printf("%s Keeping both duplicates, %s", prefix, r)
+			return fs.uniquePlannedPath(versionPath(newPath, quality.String())), false, nil
+		case skipDuplicate:
+			log.Printf("%s Skipped duplicate, %s", prefix, r)
+			file.reason = "File already exists, " + r
+			return "", false, nil
+		}
+	}
+	switch fs.OnConflict {
+	case SkipConflict:
+		log.Printf("%s Skipped, %s", prefix, reason)
+		file.reason = "File already exists, " + reason
+		return "", false, nil
+	case RenameConflict:
+		return fs.uniquePlannedPath(newPath), false, nil
+	case VersionConflict:
+		label := fs.quality(file.path).String()
+		if label == "" {
+			label = "Version"
+		}
+		return fs.uniquePlannedPath(versionPath(newPath, label)), false, nil
+	}
+	return "", false, fmt.Errorf("Destination is also planned for '%s' (try setting --overwrite or --on-conflict)", planned.Source)
+}
+
+//uniquePlannedPath is uniquePath, also avoiding planned destinations
+func (fs *fsSort) uniquePlannedPath(path string) string {
+	free := func(p string) bool {
+		_, planned := fs.planned[abs(p)]
+		_, err := os.Lstat(p)
+		return !planned && os.IsNotExist(err)
+	}
+	if free(path) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		p := stem + " (" + strconv.Itoa(n) + ")" + ext
+		if free(p) {
+			return p
+		}
+	}
+}
+
+//readPlan reads the plan file at path
+func readPlan(path string) (*Plan, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(b, plan); err != nil {
+		return nil, fmt.Errorf("Invalid plan %s: %s", path, err)
+	}
+	return plan, nil
+}
+
+//Apply performs the operations of a plan, in order and without
+//searching again. Operations whose source has changed, or whose
+//destination has since been created or changed, fail and are reported.
+func Apply(c ApplyConfig) error {
+	plan, err := readPlan(c.PlanFile)
+	if err != nil {
+		return err
+	}
+	config := plan.Config
+	config.DryRun = c.DryRun
+	config.Interactive = false
+	fs, err := newFSSort(config)
+	if err != nil {
+		return err
+	}
+	fs.sorts = map[string]*fileSort{}
+	fs.sources = map[string]bool{}
+	files := make([]*fileSort, len(plan.Operations))
+	for i, op := range plan.Operations {
+		files[i] = &fileSort{id: i + 1, path: op.Source, result: op.Result}
+		fs.sorts[op.Source] = files[i]
+	}
+	if len(files) == 0 {
+		return fs.abort(errors.New("Plan has no operations"))
+	}
+	if !fs.DryRun && !fs.NoJournal {
+		fs.journal = newJournal(fs.JournalDir)
+	}
+	if fs.DryRun {
+		log.Println(color.CyanString("[Dryrun]"))
+	}
+	for i, file := range files {
+		if err := fs.applyOperation(file, plan.Operations[i]); err != nil {
+			file.err = err
+			log.Printf("[#%d/%d] %s\n  └─> %s\n", file.id, len(fs.sorts), color.RedString(file.path), err)
+		}
+		fs.outputFile(file)
+		fs.webhookFile(file)
+	}
+	if err := fs.finishRun(); err != nil {
+		return err
+	}
+	if _, _, failed := fs.counts(); failed > 0 {
+		return fmt.Errorf("%d of %d operations failed", failed, len(files))
+	}
+	return nil
+}
+
+//applyOperation performs a planned operation
+func (fs *fsSort) applyOperation(file *fileSort, op PlannedOperation) error {
+	if op.Result == nil {
+		return errors.New("Planned operation has no result")
+	}
+	source := Fingerprint{Size: op.Size, ModTime: op.ModTime, Hash: op.Hash}
+	info, ok, err := source.matches(op.Source)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Source has changed since planning")
+	}
+	file.info = info
+	var overwrote os.FileInfo
+	if _, err := os.Lstat(op.Destination); err == nil {
+		if !op.Overwrite || op.Existing == nil {
+			return fmt.Errorf("Destination was created since planning '%s'", op.Destination)
+		}
+		existing, ok, err := op.Existing.matches(op.Destination)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("Destination has changed since planning '%s'", op.Destination)
+		}
+		overwrote = existing
+	}
+	log.Printf("[#%d/%d] %s\n  └─> %s", file.id, len(fs.sorts), color.GreenString(op.Source), color.GreenString(op.Destination))
+	if fs.DryRun {
+		file.dest = op.Destination
+		return nil
+	}
+	used, err := fs.sortInto(file, op.Source, op.Destination, overwrote)
+	if err != nil {
+		return err
+	}
+	file.dest, file.used = op.Destination, used
+	srcs, dsts := []string{}, []string{}
+	for _, c := range op.Companions {
+		srcs = append(srcs, c.Source)
+		dsts = append(dsts, c.Destination)
+	}
+	if err := fs.sortCompanions(srcs, dsts); err != nil {
+		return err
+	}
+	fs.execAfter(file)
+	return nil
+}
@@ -0,0 +1,151 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+func TestPlanApply(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Dark.S01E01.mkv", "in/Dark.S01E01.en.srt", "in/Dark.S01E02.mkv")
+	fs := testSort()
+	fs.plan = &Plan{Config: Config{
+		Targets:    []string{filepath.Join(dir, "in")},
+		TVDir:      filepath.Join(dir, "tv"),
+		Action:     MoveAction,
+		NoJournal:  true,
+		Extensions: "mkv",
+	}}
+	fs.planned = map[string]int{}
+	plan := func(name string, companions ...string) {
+		path := filepath.Join(dir, "in", name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := &fileSort{path: path, info: info, result: &Result{Name: "Dark", MType: "series"}}
+		cs := []*companion{}
+		for _, c := range companions {
+			cs = append(cs, &companion{path: filepath.Join(dir, "in", c), subtitle: true, suffix: strings.TrimPrefix(c, trimExt(name))})
+		}
+		if err := fs.planFile(file, filepath.Join(dir, "tv", name), nil, cs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan("Dark.S01E01.mkv", "Dark.S01E01.en.srt")
+	plan("Dark.S01E02.mkv")
+	b, err := json.Marshal(fs.plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planFile := filepath.Join(dir, "plan.json")
+	if err := ioutil.WriteFile(planFile, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	//sources which changed since planning are not sorted
+	if err := ioutil.WriteFile(filepath.Join(dir, "in", "Dark.S01E02.mkv"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	//dry runs change nothing
+	if err := Apply(ApplyConfig{PlanFile: planFile, DryRun: true}); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tv")); !os.IsNotExist(err) {
+		t.Fatal("dry run changed files")
+	}
+	if err := Apply(ApplyConfig{PlanFile: planFile}); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	for _, name := range []string{"tv/Dark.S01E01.mkv", "tv/Dark.S01E01.en.srt", "in/Dark.S01E02.mkv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//applied operations are not applied twice
+	if err := Apply(ApplyConfig{PlanFile: planFile}); err == nil || !strings.Contains(err.Error(), "2 of 2") {
+		t.Fatalf("expected two failures, got %v", err)
+	}
+}
+
+func TestMakePlan(t *testing.T) {
+	defer func(s func(string, string, string, int) (mediasearch.Result, error)) { searchThreshold = s }(searchThreshold)
+	searchThreshold = func(query, year, mediatype string, threshold int) (mediasearch.Result, error) {
+		if query == "dark" {
+			return mediasearch.Result{Title: "Dark", Year: "2017", Type: mediasearch.Series, Accuracy: 100}, nil
+		}
+		return mediasearch.Result{Title: "Heat", Year: "1995", Type: mediasearch.Movie, Accuracy: 100}, nil
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	write := func(name, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("in/Dark.S01E01.mkv", "larger episode")
+	write("in/Dark.S01E01.en.srt", "subtitles")
+	write("in/Heat.1995.1080p.BluRay.mkv", "heat")
+	write("in/Heat.1995.720p.WEB-DL.mkv", "HEAT")
+	write("tv/Dark S01E01.mkv", "episode")
+	//larger files overwrite, the others are renamed
+	c := PlanConfig{PlanFile: filepath.Join(dir, "plan.json")}
+	c.Targets = []string{filepath.Join(dir, "in")}
+	c.TVDir = filepath.Join(dir, "tv")
+	c.MovieDir = filepath.Join(dir, "movies")
+	c.Extensions = "mkv"
+	c.Concurrency = 1
+	c.FileLimit = 100
+	c.Recursive = true
+	c.Action = MoveAction
+	c.NoJournal = true
+	c.OverwriteIfLarger = true
+	c.OnConflict = RenameConflict
+	if err := MakePlan(c); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := readPlan(c.PlanFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dests := map[string]PlannedOperation{}
+	for _, op := range plan.Operations {
+		rel, _ := filepath.Rel(dir, op.Destination)
+		dests[filepath.ToSlash(rel)] = op
+	}
+	if len(dests) != 3 {
+		t.Fatalf("unexpected operations: %v", dests)
+	}
+	//the existing episode is overwritten, and is fingerprinted
+	dark, ok := dests["tv/Dark S01E01.mkv"]
+	if !ok || !dark.Overwrite || dark.Existing == nil || dark.Existing.Size != int64(len("episode")) {
+		t.Fatalf("unexpected episode operation: %+v", dark)
+	}
+	if len(dark.Companions) != 1 || filepath.Base(dark.Companions[0].Destination) != "Dark S01E01.en.srt" {
+		t.Fatalf("unexpected companions: %+v", dark.Companions)
+	}
+	//both copies of the movie are planned, the second is renamed
+	for _, name := range []string{"movies/Heat (1995).mkv", "movies/Heat (1995) (2).mkv"} {
+		if op, ok := dests[name]; !ok || op.Overwrite {
+			t.Fatalf("expected %s to be planned: %v", name, dests)
+		}
+	}
+	//the overwritten episode changed since planning
+	write("tv/Dark S01E01.mkv", "new episode")
+	if err := Apply(ApplyConfig{PlanFile: c.PlanFile}); err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	for _, name := range []string{"in/Dark.S01E01.mkv", "movies/Heat (1995).mkv", "movies/Heat (1995) (2).mkv"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//Quality describes the video quality of a file,
+//zero values are unknown
+type Quality struct {
+	Resolution int    //vertical resolution (2160, 1080, 720...)
+	Source     string //remux, bluray, web-dl, webrip, hdtv, dvd or cam
+	Codec      string //av1, x265, x264 or xvid
+	HDR        string //dv, hdr10+, hdr10 or hlg
+	Proper     int    //number of propers/repacks
+}
+
+//DefaultQualityRanking is the order of importance of
+//the quality attributes when comparing duplicates
+const DefaultQualityRanking = "resolution,source,hdr,codec,proper"
+
+var (
+	qualityTags       = regexp.MustCompile(`\b((19|20)\d\d|s\d+e\d+|(2160|1440|1080|720|576|480)[pi]|4k|uhd)\b`)
+	qualityResolution = regexp.MustCompile(`\b(2160|1440|1080|720|576|480)[pi]\b|\b(4k|uhd)\b`)
+	qualitySource     = regexp.MustCompile(`\b(remux|blu-?ray|bd-?rip|br-?rip|web-?dl|web-?rip|web|hdtv|pdtv|dvd-?rip|dvd|cam|telesync|ts)\b`)
+	qualityCodec      = regexp.MustCompile(`\b(av1|[xh]\.?265|hevc|[xh]\.?264|avc|xvid|divx)\b`)
+	qualityHDR        = regexp.MustCompile(`\b(hdr10\+|dv\b|dovi\b|dolby[ .]?vision\b|hdr10plus\b|hdr10\b|hdr\b|hlg\b)`)
+	qualityProper     = regexp.MustCompile(`\b(proper|repack|rerip)(\d)?\b`)
+	//scores of each attribute's values, higher is better
+	qualityScores = map[string]map[string]int{
+		"source": {"cam": 1, "dvd": 2, "hdtv": 3, "webrip": 4, "web-dl": 5, "bluray": 6, "remux": 7},
+		"codec":  {"xvid": 1, "x264": 2, "x265": 3, "av1": 4},
+		"hdr":    {"hlg": 1, "hdr10": 2, "hdr10+": 3, "dv": 4},
+	}
+)
+
+//parseQuality parses the quality tags of a release name
+func parseQuality(name string) Quality {
+	name = strings.ToLower(name)
+	q := Quality{}
+	if m := qualityResolution.FindStringSubmatch(name); m != nil {
+		if m[1] != "" {
+			q.Resolution, _ = strconv.Atoi(m[1])
+		} else {
+			q.Resolution = 2160
+		}
+	}
+	//titles may contain source words ("Charlotte's Web"), so the
+	//source is the last one after the year, episode or resolution
+	tags := name
+	if loc := qualityTags.FindStringIndex(name); loc != nil {
+		tags = name[loc[1]:]
+	}
+	if ms := qualitySource.FindAllStringSubmatch(tags, -1); ms != nil {
+		m := ms[len(ms)-1]
+		switch s := strings.Replace(m[1], "-", "", -1); s {
+		case "remux":
+			q.Source = "remux"
+		case "bluray", "bdrip", "brrip":
+			q.Source = "bluray"
+		case "webdl", "web":
+			q.Source = "web-dl"
+		case "webrip":
+			q.Source = "webrip"
+		case "hdtv", "pdtv":
+			q.Source = "hdtv"
+		case "dvdrip", "dvd":
+			q.Source = "dvd"
+		default:
+			q.Source = "cam"
+		}
+		//remuxes are often tagged "bluray remux"
+		if q.Source == "bluray" && strings.Contains(name, "remux") {
+			q.Source = "remux"
+		}
+	}
+	if m := qualityCodec.FindStringSubmatch(name); m != nil {
+		q.Codec = normalizeCodec(m[1])
+	}
+	if m := qualityHDR.FindStringSubmatch(name); m != nil {
+		q.HDR = normalizeHDR(m[1])
+	}
+	for _, m := range qualityProper.FindAllStringSubmatch(name, -1) {
+		n := 1
+		if m[2] != "" {
+			n, _ = strconv.Atoi(m[2])
+		}
+		if n > q.Proper {
+			q.Proper = n
+		}
+	}
+	return q
+}
+
+func normalizeCodec(c string) string {
+	switch strings.Replace(c, ".", "", -1) {
+	case "av1":
+		return "av1"
+	case "x265", "h265", "hevc":
+		return "x265"
+	case "x264", "h264", "avc":
+		return "x264"
+	case "xvid", "divx", "mpeg4":
+		return "xvid"
+	}
+	return ""
+}
+
+func normalizeHDR(h string) string {
+	switch strings.Replace(strings.Replace(h, " ", "", -1), ".", "", -1) {
+	case "dv", "dovi", "dolbyvision":
+		return "dv"
+	case "hdr10+", "hdr10plus":
+		return "hdr10+"
+	case "hdr10", "hdr":
+		return "hdr10"
+	case "hlg":
+		return "hlg"
+	}
+	return ""
+}
+
+//String returns a short label (1080p BluRay x265)
+func (q Quality) String() string {
+	parts := []string{}
+	if q.Resolution > 0 {
+		parts = append(parts, strconv.Itoa(q.Resolution)+"p")
+	}
+	switch q.Source {
+	case "":
+	case "bluray":
+		parts = append(parts, "BluRay")
+	case "web-dl":
+		parts = append(parts, "WEB-DL")
+	case "webrip":
+		parts = append(parts, "WEBRip")
+	case "dvd", "cam", "hdtv":
+		parts = append(parts, strings.ToUpper(q.Source))
+	default:
+		parts = append(parts, strings.Title(q.Source))
+	}
+	switch q.HDR {
+	case "":
+	case "dv":
+		parts = append(parts, "DV")
+	default:
+		parts = append(parts, strings.ToUpper(q.HDR))
+	}
+	if q.Codec != "" {
+		parts = append(parts, q.Codec)
+	}
+	if q.Proper > 0 {
+		parts = append(parts, "PROPER")
+	}
+	return strings.Join(parts, " ")
+}
+
+//value returns the score of the attribute, 0 is unknown
+func (q Quality) value(attr string) int {
+	switch attr {
+	case "resolution":
+		return q.Resolution
+	case "source":
+		return qualityScores[attr][q.Source]
+	case "codec":
+		return qualityScores[attr][q.Codec]
+	case "hdr":
+		return qualityScores[attr][q.HDR]
+	case "proper":
+		//no proper is known to be "0 propers"
+		return q.Proper + 1
+	}
+	return 0
+}
+
+//parseRanking validates a comma separated quality ranking
+func parseRanking(s string) ([]string, error) {
+	if s == "" {
+		s = DefaultQualityRanking
+	}
+	ranking := []string{}
+	for _, attr := range strings.Split(s, ",") {
+		attr = strings.ToLower(strings.TrimSpace(attr))
+		switch attr {
+		case "resolution", "source", "codec", "hdr", "proper":
+			ranking = append(ranking, attr)
+		case "":
+		default:
+			return nil, fmt.Errorf("Unknown quality attribute: %s", attr)
+		}
+	}
+	return ranking, nil
+}
+
+//compare returns 1 when q is better than other, -1 when it's worse and
+//0 when the ranked attributes are equal. Attributes are only compared
+//when known on both sides, ok is false when none could be compared.
+func (q Quality) compare(other Quality, ranking []string) (result int, ok bool) {
+	for _, attr := range ranking {
+		a, b := q.value(attr), other.value(attr)
+		if a == 0 || b == 0 {
+			continue
+		}
+		if a > b {
+			return 1, true
+		} else if a < b {
+			return -1, true
+		}
+		//propers alone don't make files comparable
+		if attr != "proper" {
+			ok = true
+		}
+	}
+	return 0, ok
+}
+
+//duplicate decisions
+type qualityDecision int
+
+const (
+	replaceDuplicate qualityDecision = iota
+	keepBoth
+	skipDuplicate
+)
+
+//compareQuality decides what to do with the incoming file at
+//src, given the existing file at dst
+func (fs *fsSort) compareQuality(src, dst string) (qualityDecision, Quality, string) {
+	incoming := fs.quality(src)
+	existing := fs.quality(dst)
+	c, ok := incoming.compare(existing, fs.ranking)
+	switch {
+	case !ok:
+		return skipDuplicate, incoming, "unable to compare quality (try setting --probe)"
+	case c > 0:
+		return replaceDuplicate, incoming, fmt.Sprintf("%s is better than %s", incoming, existing)
+	case c < 0:
+		return skipDuplicate, incoming, fmt.Sprintf("%s is worse than %s", incoming, existing)
+	case incoming != existing && incoming.String() != "":
+		return keepBoth, incoming, fmt.Sprintf("%s is different to %s", incoming, existing)
+	}
+	return skipDuplicate, incoming, fmt.Sprintf("%s is the same as %s", incoming, existing)
+}
+
+//quality parses the file name, and when probing,
+//merges in the quality detected by ffprobe
+func (fs *fsSort) quality(path string) Quality {
+	q := parseQuality(trimExt(filepath.Base(path)))
+	//sorted names rarely keep their quality tags,
+	//so use the name the file was sorted from
+	if q == (Quality{}) {
+		if name := fs.sortedFrom(path); name != "" {
+			q = parseQuality(trimExt(filepath.Base(name)))
+		}
+	}
+	if fs.Probe {
+		if p, err := probeQuality(path); err != nil {
+			fs.verbf("probe failed: %s: %s", path, err)
+		} else {
+			if p.Resolution > 0 {
+				q.Resolution = p.Resolution
+			}
+			if p.Codec != "" {
+				q.Codec = p.Codec
+			}
+			if p.HDR != "" {
+				q.HDR = p.HDR
+			}
+		}
+	}
+	return q
+}
+
+func trimExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+//sortedFrom returns the path which the file at path was last
+//sorted from, found in the journals of previous runs
+func (fs *fsSort) sortedFrom(path string) string {
+	fs.originsLock.Lock()
+	defer fs.originsLock.Unlock()
+	if fs.origins == nil {
+		fs.origins = map[string]string{}
+		runs, err := listRuns(fs.JournalDir)
+		if err != nil {
+			fs.verbf("failed to list journals: %s", err)
+		}
+		//oldest first, so the latest sort of each path wins
+		for _, run := range runs {
+			ops, err := readJournal(filepath.Join(fs.JournalDir, run+journalExt))
+			if err != nil {
+				fs.verbf("failed to read journal: %s", err)
+				continue
+			}
+			for _, op := range ops {
+				fs.addOrigin(op)
+			}
+		}
+	}
+	return fs.origins[abs(path)]
+}
+
+//addOrigin remembers the source of a sorted file
+func (fs *fsSort) addOrigin(op Operation) {
+	switch op.Action {
+	case WriteAction, DeleteDupeAction, LinkDupeAction:
+		return
+	}
+	if op.Src != "" && !op.Companion {
+		fs.origins[op.Dst] = op.Src
+	}
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName     string `json:"codec_name"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		ColorTransfer string `json:"color_transfer"`
+		SideDataList  []struct {
+			SideDataType string `json:"side_data_type"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+//probeQuality detects the resolution, codec
+//and HDR format of the first video stream
+func probeQuality(path string) (Quality, error) {
+	q := Quality{}
+	out, err := exec.Command("ffprobe", "-v", "quiet", "-print_format", "json",
+		"-show_streams", "-select_streams", "v:0", path).Output()
+	if err != nil {
+		return q, err
+	}
+	probe := ffprobeOutput{}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return q, err
+	}
+	if len(probe.Streams) == 0 {
+		return q, fmt.Errorf("No video stream")
+	}
+	s := probe.Streams[0]
+	//use the width, movies are often cropped vertically
+	switch {
+	case s.Width >= 3200:
+		q.Resolution = 2160
+	case s.Width >= 2200:
+		q.Resolution = 1440
+	case s.Width >= 1800:
+		q.Resolution = 1080
+	case s.Width >= 1200:
+		q.Resolution = 720
+	case s.Height >= 576:
+		q.Resolution = 576
+	case s.Height > 0:
+		q.Resolution = 480
+	}
+	q.Codec = normalizeCodec(s.CodecName)
+	switch s.ColorTransfer {
+	case "smpte2084":
+		q.HDR = "hdr10"
+	case "arib-std-b67":
+		q.HDR = "hlg"
+	}
+	for _, d := range s.SideDataList {
+		if strings.Contains(strings.ToLower(d.SideDataType), "dovi") {
+			q.HDR = "dv"
+		}
+	}
+	return q, nil
+}
+
+//versionPath returns the path of another version of the
+//file at path, using the Plex/Jellyfin "<name> - <label>" style
+func versionPath(path, label string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + " - " + label + ext
+}
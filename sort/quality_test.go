@@ -0,0 +1,99 @@
+package mediasort
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseQuality(t *testing.T) {
+	for name, expect := range map[string]Quality{
+		"Movie.2010.2160p.UHD.BluRay.REMUX.HDR.HEVC.Atmos-GRP": {Resolution: 2160, Source: "remux", Codec: "x265", HDR: "hdr10"},
+		"Movie 2010 1080p WEB-DL DDP5.1 H.264-GRP":             {Resolution: 1080, Source: "web-dl", Codec: "x264"},
+		"Show.S01E02.REPACK.720p.HDTV.x264-GRP":                {Resolution: 720, Source: "hdtv", Codec: "x264", Proper: 1},
+		"Movie.2010.2160p.WEBRip.DV.x265":                      {Resolution: 2160, Source: "webrip", Codec: "x265", HDR: "dv"},
+		"Movie.2010.2160p.BluRay.HDR10+.x265":                  {Resolution: 2160, Source: "bluray", Codec: "x265", HDR: "hdr10+"},
+		"Movie (2010)":                                         {},
+		"Charlotte's Web 2006 1080p BluRay":                    {Resolution: 1080, Source: "bluray"},
+		"Cam.2019.720p.WEBRip.x264":                            {Resolution: 720, Source: "webrip", Codec: "x264"},
+		"The.TS.Files.S01E01.HDTV":                             {Source: "hdtv"},
+		"Web.of.Lies.2010":                                     {},
+	} {
+		if q := parseQuality(name); q != expect {
+			t.Fatalf("%s: expected %+v, got %+v", name, expect, q)
+		}
+	}
+}
+
+func TestCompareQuality(t *testing.T) {
+	ranking, err := parseRanking("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		A, B   string
+		Expect int
+		OK     bool
+	}{
+		{"Movie.1080p.BluRay.x264", "Movie.720p.BluRay.x265", 1, true},
+		{"Movie.1080p.WEB-DL.x264", "Movie.1080p.BluRay.x264", -1, true},
+		{"Movie.1080p.BluRay.x264.PROPER", "Movie.1080p.BluRay.x264", 1, true},
+		{"Movie.1080p.BluRay.x264", "Movie.1080p.BluRay", 0, true},
+		{"Movie.1080p.BluRay.x264", "Movie (2010)", 0, false},
+	} {
+		c, ok := parseQuality(tc.A).compare(parseQuality(tc.B), ranking)
+		if c != tc.Expect || ok != tc.OK {
+			t.Fatalf("%s vs %s: expected %d/%v, got %d/%v", tc.A, tc.B, tc.Expect, tc.OK, c, ok)
+		}
+	}
+	//codecs ranked above resolution
+	ranking, _ = parseRanking("codec,resolution")
+	if c, _ := parseQuality("Movie.720p.x265").compare(parseQuality("Movie.1080p.x264"), ranking); c != 1 {
+		t.Fatalf("expected codec ranking to prefer x265")
+	}
+	if _, err := parseRanking("resolution,size"); err == nil {
+		t.Fatal("expected unknown attribute error")
+	}
+}
+
+func TestCompareSortedQuality(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Movie.2010.1080p.BluRay.x264.mkv", "in/Movie.2010.2160p.BluRay.x265.mkv")
+	in := filepath.Join(dir, "in")
+	dst := filepath.Join(dir, "out", "Movie (2010).mkv")
+	fs := testSort()
+	fs.Action = CopyAction
+	fs.JournalDir = filepath.Join(dir, "journal")
+	ranking, err := parseRanking("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.ranking = ranking
+	//a previous run sorted the 1080p copy
+	fs.journal = newJournal(fs.JournalDir)
+	src := filepath.Join(in, "Movie.2010.1080p.BluRay.x264.mkv")
+	dirs, err := mkdirAll(filepath.Dir(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, err := fs.action(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.record(fs.newOperation(used, src, dst, nil, dirs))
+	if err := fs.closeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	//the templated name has no tags, the journal has the original
+	fs = testSort()
+	fs.JournalDir = filepath.Join(dir, "journal")
+	fs.ranking = ranking
+	d, q, reason := fs.compareQuality(filepath.Join(in, "Movie.2010.2160p.BluRay.x265.mkv"), dst)
+	if d != replaceDuplicate {
+		t.Fatalf("expected replace, got %d (%s)", d, reason)
+	}
+	if q.Resolution != 2160 {
+		t.Fatalf("expected 2160p, got %s", q)
+	}
+}
@@ -0,0 +1,188 @@
+package mediasort
+
+import (
+	"log"
+	"sort"
+
+	"github.com/fatih/color"
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+//Status is the outcome of sorting a file
+type Status string
+
+const (
+	//SortedStatus files were placed in the library
+	//(or would have been, in a dry run)
+	SortedStatus Status = "sorted"
+	//SkippedStatus files were left in place or set aside
+	SkippedStatus Status = "skipped"
+	//FailedStatus files could not be sorted
+	FailedStatus Status = "failed"
+)
+
+//Entry describes what happened to a file in a run
+type Entry struct {
+	Status      Status  `json:"status"`
+	Source      string  `json:"source"`
+	Destination string  `json:"destination,omitempty"`
+	Action      string  `json:"action,omitempty"`
+	Parsed      *Parsed `json:"parsed,omitempty"`
+	Match       *Match  `json:"match,omitempty"`
+	//Reason the file was skipped
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+//Parsed is what was parsed from the file's path
+type Parsed struct {
+	Query   string `json:"query"`
+	Year    string `json:"year,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+}
+
+//Match is the search result the file was sorted by
+type Match struct {
+	Title    string `json:"title"`
+	Year     string `json:"year,omitempty"`
+	Type     string `json:"type"`
+	Accuracy int    `json:"accuracy"`
+	IMDBID   string `json:"imdb_id,omitempty"`
+	TMDBID   int    `json:"tmdb_id,omitempty"`
+	TVDBID   int    `json:"tvdb_id,omitempty"`
+}
+
+//Summary counts the outcomes of a run
+type Summary struct {
+	RunID      string   `json:"run_id,omitempty"`
+	DryRun     bool     `json:"dry_run,omitempty"`
+	Sorted     int      `json:"sorted"`
+	Skipped    int      `json:"skipped"`
+	Failed     int      `json:"failed"`
+	HookErrors []string `json:"hook_errors,omitempty"`
+	//Error aborted the run
+	Error string `json:"error,omitempty"`
+}
+
+//Report describes each file of a run
+type Report struct {
+	Summary Summary `json:"summary"`
+	Sorted  []Entry `json:"sorted"`
+	Skipped []Entry `json:"skipped"`
+	Failed  []Entry `json:"failed"`
+}
+
+func (file *fileSort) status() Status {
+	if file.err != nil && file.err != errSkipped {
+		return FailedStatus
+	}
+	if file.dest != "" {
+		return SortedStatus
+	}
+	return SkippedStatus
+}
+
+//entry describes the file's outcome
+func (fs *fsSort) entry(file *fileSort) Entry {
+	e := Entry{
+		Status:      file.status(),
+		Source:      file.path,
+		Destination: file.dest,
+		Action:      file.used.String(),
+		Reason:      file.reason,
+	}
+	if fs.DryRun && file.dest != "" && len(fs.steps) > 0 {
+		e.Action = fs.steps[0].String()
+	}
+	if file.err != nil {
+		if file.err == errSkipped {
+			e.Reason = file.err.Error()
+		} else {
+			e.Error = file.err.Error()
+		}
+	}
+	//parse again, since matching replaces the parsed year and type
+	if p, err := runPathParse(file.path, fs.NumDirs); err == nil {
+		e.Parsed = &Parsed{Query: p.Query, Year: p.Year, Type: p.MType}
+		if p.MType == string(mediasearch.Series) {
+			e.Parsed.Season = p.Season
+			if p.Episode != -1 {
+				e.Parsed.Episode = p.Episode
+			}
+		}
+	}
+	if r := file.result; r != nil {
+		e.Match = &Match{
+			Title:    r.Name,
+			Year:     r.Year,
+			Type:     r.MType,
+			Accuracy: r.Accuracy,
+			IMDBID:   r.IMDBID,
+			TMDBID:   r.TMDBID,
+			TVDBID:   r.TVDBID,
+		}
+	}
+	return e
+}
+
+//report describes the outcome of each file in the run
+func (fs *fsSort) report() *Report {
+	r := &Report{Sorted: []Entry{}, Skipped: []Entry{}, Failed: []Entry{}}
+	paths := []string{}
+	for path := range fs.sorts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		e := fs.entry(fs.sorts[path])
+		switch e.Status {
+		case SortedStatus:
+			r.Sorted = append(r.Sorted, e)
+		case SkippedStatus:
+			r.Skipped = append(r.Skipped, e)
+		case FailedStatus:
+			r.Failed = append(r.Failed, e)
+		}
+	}
+	r.Summary = fs.summary()
+	return r
+}
+
+//summary counts the outcomes of the run
+func (fs *fsSort) summary() Summary {
+	s := Summary{DryRun: fs.DryRun}
+	if fs.journal != nil {
+		s.RunID = fs.journal.runID
+	}
+	s.Sorted, s.Skipped, s.Failed = fs.counts()
+	fs.hookLock.Lock()
+	s.HookErrors = append(s.HookErrors, fs.hookErrors...)
+	fs.hookLock.Unlock()
+	return s
+}
+
+//counts returns the number of files with each status
+func (fs *fsSort) counts() (sorted, skipped, failed int) {
+	for _, file := range fs.sorts {
+		switch file.status() {
+		case SortedStatus:
+			sorted++
+		case SkippedStatus:
+			skipped++
+		case FailedStatus:
+			failed++
+		}
+	}
+	return
+}
+
+//summarize logs the outcome of the run
+func (fs *fsSort) summarize() {
+	sorted, skipped, failed := fs.counts()
+	log.Printf("Sorted %d, skipped %d and failed %d files", sorted, skipped, failed)
+	for _, msg := range fs.hookErrors {
+		log.Printf("Hook failed: %s", color.RedString(msg))
+	}
+}
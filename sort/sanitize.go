@@ -0,0 +1,121 @@
+package mediasort
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+//Sanitize names a filename sanitization profile
+type Sanitize string
+
+const (
+	//PosixSanitize only removes characters which are awkward in a shell (the default)
+	PosixSanitize Sanitize = "posix"
+	//WindowsSanitize also removes characters, trailing dots and reserved
+	//names which are invalid on Windows and SMB shares
+	WindowsSanitize Sanitize = "windows"
+	//ASCIISanitize is WindowsSanitize, restricted to plain ascii
+	ASCIISanitize Sanitize = "ascii"
+)
+
+var (
+	windowsChars    = strings.NewReplacer(`"`, "", `?`, "", `*`, "-", `<`, "-", `>`, "-", `|`, "-", `\`, "-")
+	windowsReserved = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com\d|lpt\d)(\.|$)`)
+	//episode markers and trailing years/ids are kept when truncating
+	episodeMarker = regexp.MustCompile(`(?i)\bS\d{1,3}E\d{1,4}((-?E|-)\d{1,4})*\b|\b\d{4}-\d{2}-\d{2}\b`)
+	folderSuffix  = regexp.MustCompile(`(\s*(\(\d{4}\)|\{[^{}]*\}|\[[^\[\]]*\]))+$`)
+)
+
+func (s Sanitize) validate() error {
+	switch s {
+	case "", PosixSanitize, WindowsSanitize, ASCIISanitize:
+		return nil
+	}
+	return fmt.Errorf("Unknown sanitize profile: %s", s)
+}
+
+//sanitizePath cleans the templated path s using the given profile,
+//and truncates each path component to max bytes (0 disables)
+func sanitizePath(s string, profile Sanitize, max int) string {
+	if profile == ASCIISanitize {
+		s = ascii(s)
+	}
+	s = fixPath(s)
+	if (profile == PosixSanitize || profile == "") && max <= 0 {
+		return s
+	}
+	//sanitize each component, templates may use either separator
+	parts := strings.Split(strings.Replace(s, sep, "/", -1), "/")
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if profile == WindowsSanitize || profile == ASCIISanitize {
+			part = windowsChars.Replace(part)
+			//windows silently drops trailing dots and spaces
+			part = strings.TrimRight(strings.TrimLeft(part, " "), ". ")
+			if windowsReserved.MatchString(part) {
+				part = "_" + part
+			}
+		}
+		if max > 0 && len(part) > max {
+			part = truncateName(part, last, max)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, sep)
+}
+
+//truncateName shortens name to max bytes. File names keep their
+//extension and episode marker, folder names keep their year and IDs.
+func truncateName(name string, file bool, max int) string {
+	head, keep, tail := name, "", ""
+	if file {
+		ext := getExtension(name)
+		head = strings.TrimSuffix(name, ext)
+		tail = ext
+		if loc := episodeMarker.FindStringIndex(head); loc != nil {
+			//include the separator before the marker
+			for loc[0] > 0 && strings.IndexByte(" -._", head[loc[0]-1]) >= 0 {
+				loc[0]--
+			}
+			keep = head[loc[0]:loc[1]]
+			//text after the marker is truncated first
+			after := head[loc[1]:]
+			head = head[:loc[0]]
+			budget := max - len(head) - len(keep) - len(tail)
+			if budget < len(after) {
+				after = truncateBytes(after, budget)
+			}
+			keep += after
+		}
+	} else if loc := folderSuffix.FindStringIndex(head); loc != nil {
+		head, keep = head[:loc[0]], head[loc[0]:]
+	}
+	budget := max - len(keep) - len(tail)
+	if budget <= 0 {
+		//nothing left to truncate, cut from the end
+		return truncateBytes(name, max)
+	}
+	return truncateBytes(head, budget) + keep + tail
+}
+
+//truncateBytes cuts s to at most n bytes on a word (or rune)
+//boundary, then removes any dangling separators
+func truncateBytes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	cut := s[:n]
+	//prefer cutting between words
+	if i := strings.LastIndexByte(cut, ' '); i > 0 && strings.IndexByte(" -._", s[n]) == -1 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " -._,")
+}
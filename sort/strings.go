@@ -20,10 +20,11 @@ var (
 	joinedepiseason = regexp.MustCompile(`^(.+?\b)(\d)(\d{2})\b`)
 	partnum         = regexp.MustCompile(`^(.+?\b)(\d{1,2})\b`)
 	partof          = regexp.MustCompile(`(?i)^(.+?\b)(\d{1,3})\s*of\s*\d{1,3}\b`)
+	extra           = regexp.MustCompile(`(?i)-(behindthescenes|deleted|featurette|interview|scene|short|trailer|other)$`)
 	extRe           = regexp.MustCompile(`\.\w+$`)
 	apost           = regexp.MustCompile(`'`)
 	colon           = regexp.MustCompile(`:`)
-	invalidChars    = regexp.MustCompile(`[^\p{Greek}\pP\pN\p{L}\_\-\.\ \(\)\/\\]`)
+	invalidChars    = regexp.MustCompile(`[^\p{Greek}\pP\pN\p{L}\_\-\.\ \(\)\/\\\=]`)
 	doubleDash      = regexp.MustCompile(`-(\s*-\s*)+ `)
 	doubleSpace     = regexp.MustCompile(`\s+`)
 	sep             = string(filepath.Separator)
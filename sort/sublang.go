@@ -0,0 +1,289 @@
+package mediasort
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//language holds the ISO 639-2 codes of a language (alpha3b is the
+//bibliographic code when it differs) and its names as seen in
+//subtitle file names, in english and natively (ascii folded)
+type language struct {
+	alpha3, alpha3b string
+	names           []string
+}
+
+//languages is keyed by ISO 639-1 code
+var languages = map[string]language{
+	"ar": {"ara", "", []string{"arabic"}},
+	"bg": {"bul", "", []string{"bulgarian", "bulgarski"}},
+	"ca": {"cat", "", []string{"catalan", "catala"}},
+	"cs": {"ces", "cze", []string{"czech", "cesky", "cestina"}},
+	"da": {"dan", "", []string{"danish", "dansk"}},
+	"de": {"deu", "ger", []string{"german", "deutsch"}},
+	"el": {"ell", "gre", []string{"greek", "ellinika"}},
+	"en": {"eng", "", []string{"english"}},
+	"es": {"spa", "", []string{"spanish", "espanol", "castellano", "latino"}},
+	"et": {"est", "", []string{"estonian", "eesti"}},
+	"fa": {"fas", "per", []string{"persian", "farsi"}},
+	"fi": {"fin", "", []string{"finnish", "suomi"}},
+	"fr": {"fra", "fre", []string{"french", "francais"}},
+	"he": {"heb", "", []string{"hebrew", "ivrit"}},
+	"hi": {"hin", "", []string{"hindi"}},
+	"hr": {"hrv", "", []string{"croatian", "hrvatski"}},
+	"hu": {"hun", "", []string{"hungarian", "magyar"}},
+	"id": {"ind", "", []string{"indonesian", "bahasa"}},
+	"is": {"isl", "ice", []string{"icelandic", "islenska"}},
+	"it": {"ita", "", []string{"italian", "italiano"}},
+	"ja": {"jpn", "", []string{"japanese", "nihongo"}},
+	"ko": {"kor", "", []string{"korean", "hangugeo"}},
+	"lt": {"lit", "", []string{"lithuanian", "lietuviu"}},
+	"lv": {"lav", "", []string{"latvian", "latviesu"}},
+	"ms": {"msa", "may", []string{"malay", "melayu"}},
+	"nl": {"nld", "dut", []string{"dutch", "nederlands", "flemish"}},
+	"no": {"nor", "", []string{"norwegian", "norsk"}},
+	"pl": {"pol", "", []string{"polish", "polski"}},
+	"pt": {"por", "", []string{"portuguese", "portugues", "brazilian"}},
+	"ro": {"ron", "rum", []string{"romanian", "romana"}},
+	"ru": {"rus", "", []string{"russian", "russkiy"}},
+	"sk": {"slk", "slo", []string{"slovak", "slovensky"}},
+	"sl": {"slv", "", []string{"slovenian", "slovene", "slovenscina"}},
+	"sr": {"srp", "", []string{"serbian", "srpski"}},
+	"sv": {"swe", "", []string{"swedish", "svenska"}},
+	"th": {"tha", "", []string{"thai"}},
+	"tr": {"tur", "", []string{"turkish", "turkce"}},
+	"uk": {"ukr", "", []string{"ukrainian", "ukrainska"}},
+	"vi": {"vie", "", []string{"vietnamese", "tieng viet"}},
+	"zh": {"zho", "chi", []string{"chinese", "mandarin", "cantonese"}},
+}
+
+//languageCode returns the ISO 639-1 code of the provided ISO 639-1
+//or 639-2 language code or language name, or "" when unknown
+func languageCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	if _, ok := languages[s]; ok {
+		return s
+	}
+	s = strings.ToLower(ascii(s))
+	for code, l := range languages {
+		if l.alpha3 == s || l.alpha3b == s {
+			return code
+		}
+		for _, name := range l.names {
+			if name == s {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+//stopwords are the most common words of each latin or
+//cyrillic script language, used to identify subtitle text
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "that", "is", "it", "to", "of", "what", "this", "not", "have", "are", "was", "for", "with", "your", "me", "we", "he"},
+	"fr": {"le", "la", "les", "de", "et", "est", "un", "une", "je", "tu", "vous", "pas", "que", "qui", "ce", "il", "elle", "nous", "pour", "dans", "mais", "avec", "suis", "c'est"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "du", "sie", "es", "wir", "ein", "eine", "zu", "mit", "auf", "was", "den", "ja", "auch", "mir", "mich"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "es", "no", "un", "una", "por", "para", "con", "lo", "se", "mi", "pero", "está", "eso", "estoy", "muy", "qué"},
+	"it": {"il", "lo", "la", "di", "che", "e", "non", "un", "una", "per", "sono", "mi", "ti", "ma", "questo", "cosa", "io", "hai", "ho", "con", "della", "perché"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "não", "um", "uma", "para", "com", "você", "eu", "isso", "está", "mas", "por", "meu", "se", "muito", "ele"},
+	"nl": {"de", "het", "een", "en", "van", "ik", "je", "niet", "is", "dat", "wat", "we", "zijn", "op", "maar", "met", "hij", "ze", "hebben", "dit", "voor", "jij"},
+	"sv": {"och", "att", "det", "som", "jag", "du", "inte", "en", "ett", "har", "vi", "är", "på", "med", "kan", "var", "den", "han", "hon", "vad", "mig", "till"},
+	"da": {"og", "at", "det", "jeg", "du", "ikke", "er", "en", "et", "har", "vi", "på", "med", "kan", "der", "den", "han", "hun", "hvad", "mig", "dig", "af"},
+	"no": {"og", "at", "det", "jeg", "du", "ikke", "er", "en", "et", "har", "vi", "på", "med", "kan", "der", "den", "han", "hun", "hva", "meg", "deg", "av"},
+	"pl": {"nie", "to", "jest", "się", "w", "na", "i", "z", "co", "że", "tak", "jak", "ja", "do", "mi", "ale", "czy", "tu", "już", "jestem"},
+	"cs": {"je", "to", "se", "na", "že", "a", "v", "jsem", "co", "ale", "tak", "jak", "mi", "by", "už", "ne", "do", "tady", "jsi"},
+	"ro": {"și", "de", "nu", "că", "în", "la", "să", "e", "este", "un", "o", "ce", "pe", "cu", "am", "mai", "asta", "eu", "ești"},
+	"tr": {"bir", "ve", "bu", "ne", "da", "de", "ben", "sen", "mi", "için", "ama", "o", "var", "yok", "çok", "değil", "evet", "hayır"},
+	"hu": {"a", "az", "és", "hogy", "nem", "is", "egy", "van", "meg", "de", "mi", "ez", "én", "te", "csak", "már"},
+	"fi": {"ja", "on", "ei", "se", "että", "mitä", "minä", "sinä", "hän", "me", "te", "ne", "kun", "niin", "olen", "tämä"},
+	"id": {"yang", "dan", "di", "ini", "itu", "aku", "kau", "tidak", "ada", "saya", "kamu", "apa", "akan", "ke", "dengan"},
+	"ru": {"и", "в", "не", "что", "он", "на", "я", "с", "это", "как", "ты", "мы", "вы", "но", "да", "нет", "так", "меня"},
+	"uk": {"і", "не", "що", "це", "як", "ти", "ми", "ви", "але", "так", "ні", "я", "він", "та", "до", "мене", "й"},
+	"bg": {"и", "да", "не", "се", "на", "е", "за", "това", "ли", "ще", "съм", "си", "как", "ти", "аз", "какво"},
+	"sr": {"је", "да", "не", "и", "у", "се", "на", "то", "сам", "си", "шта", "ли", "али"},
+}
+
+//scripts which identify a single language
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Thai, "th"},
+	{unicode.Arabic, "ar"},
+	{unicode.Han, "zh"},
+}
+
+var (
+	subTimestamps = regexp.MustCompile(`\d+:\d+:\d+[,\.]\d+`)
+	subMarkup     = regexp.MustCompile(`<[^>]*>|\{[^}]*\}|\\[nNh]`)
+	subIdxLang    = regexp.MustCompile(`(?m)^id:\s*([a-z]{2,3})`)
+	persian       = regexp.MustCompile(`[پچژگ]`)
+)
+
+//detectLanguage identifies the language of the subtitle
+//file at path, returning "" when unsure
+func detectLanguage(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	//vobsub subtitles declare their language in the .idx
+	if ext == ".sub" {
+		path = strings.TrimSuffix(path, filepath.Ext(path)) + ".idx"
+		ext = ".idx"
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return ""
+	}
+	text := decodeText(b)
+	switch ext {
+	case ".idx":
+		if m := subIdxLang.FindStringSubmatch(text); len(m) > 0 {
+			return languageCode(m[1])
+		}
+		return ""
+	case ".srt", ".ass", ".ssa", ".vtt", ".smi":
+		return identifyLanguage(subtitleText(text, ext))
+	}
+	return ""
+}
+
+//decodeText converts subtitle bytes into a string, subtitles are
+//commonly UTF-8, UTF-16 (with a BOM) or Windows-1252
+func decodeText(b []byte) string {
+	if len(b) >= 2 && (b[0] == 0xFF && b[1] == 0xFE || b[0] == 0xFE && b[1] == 0xFF) {
+		le := b[0] == 0xFF
+		u := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			if le {
+				u = append(u, uint16(b[i])|uint16(b[i+1])<<8)
+			} else {
+				u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+			}
+		}
+		return string(utf16.Decode(u))
+	}
+	if utf8.Valid(b) {
+		return strings.TrimPrefix(string(b), "\ufeff")
+	}
+	//latin-1, the first 256 code points match
+	rs := make([]rune, len(b))
+	for i, c := range b {
+		rs[i] = rune(c)
+	}
+	return string(rs)
+}
+
+//subtitleText removes numbering, timestamps, headers
+//and markup, leaving only the spoken text
+func subtitleText(s, ext string) string {
+	ass := ext == ".ass" || ext == ".ssa"
+	sb := strings.Builder{}
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if ass {
+			//text is the 10th field of dialogue lines
+			parts := strings.SplitN(line, ",", 10)
+			if !strings.HasPrefix(line, "Dialogue:") || len(parts) != 10 {
+				continue
+			}
+			line = parts[9]
+		} else if subTimestamps.MatchString(line) || line == "WEBVTT" {
+			continue
+		}
+		sb.WriteString(subMarkup.ReplaceAllString(line, " "))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+//identifyLanguage returns the ISO 639-1 code of the language of the
+//given text, based on its script and stopwords, or "" when unsure
+func identifyLanguage(text string) string {
+	//count letters of each script
+	letters, latin, cyrillic := 0, 0, 0
+	scripts := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+		} else if unicode.Is(unicode.Cyrillic, r) {
+			cyrillic++
+		} else {
+			for i, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scripts[i]++
+					break
+				}
+			}
+		}
+	}
+	if letters < 50 {
+		return ""
+	}
+	//japanese also uses han, any kana means japanese
+	kana := scripts[3] + scripts[4]
+	for i, s := range scriptLanguages {
+		n := scripts[i]
+		if s.lang == "zh" && kana > 0 {
+			n += kana
+			s.lang = "ja"
+		}
+		if n*2 > letters {
+			if s.lang == "ar" && persian.MatchString(text) {
+				return "fa"
+			}
+			return s.lang
+		}
+	}
+	if latin*2 < letters && cyrillic*2 < letters {
+		return ""
+	}
+	//count stopwords of each language
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		counts[word]++
+	}
+	best, bestScore, secondScore := "", 0, 0
+	for lang, words := range stopwords {
+		score := 0
+		for _, w := range words {
+			score += counts[w]
+		}
+		if score > bestScore {
+			best, bestScore, secondScore = lang, score, bestScore
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+	//require a clear winner
+	if bestScore < 10 || bestScore*4 < secondScore*5 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,53 @@
+package mediasort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLanguageCode(t *testing.T) {
+	for in, exp := range map[string]string{
+		"en": "en", "eng": "en", "English": "en", "fre": "fr", "fra": "fr",
+		"Français": "fr", "deutsch": "de", "pob": "", "brazilian": "pt", "": "",
+	} {
+		if got := languageCode(in); got != exp {
+			t.Errorf("languageCode(%q) = %q, expected %q", in, got, exp)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	srt := func(lines ...string) string {
+		sb := strings.Builder{}
+		for i, l := range lines {
+			sb.WriteString(strings.Join([]string{
+				string(rune('1' + i%9)), "00:00:01,000 --> 00:00:02,000", l, "", ""}, "\n"))
+		}
+		return strings.Repeat(sb.String(), 4)
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	for _, tc := range []struct {
+		Name, Content, Expect string
+	}{
+		{"a.srt", srt("What are you doing with the car?", "<i>I have to go, this is not your fault.</i>", "We know that he was here."), "en"},
+		{"b.srt", srt("Je ne sais pas ce que tu veux.", "C'est pour vous, mais il est pas là.", "Nous avons une maison dans la ville."), "fr"},
+		{"c.srt", srt("Ich weiß nicht, was du willst.", "Das ist nicht mein Auto, und es ist kaputt.", "Wir sind auf dem Weg zu dir."), "de"},
+		{"d.srt", srt("Я не знаю, что ты хочешь.", "Это не моя машина, и она сломана.", "Мы на пути к тебе, да?"), "ru"},
+		{"e.ass", "[Script Info]\nTitle: English\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+			strings.Repeat("Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\an8}お前はもう死んでいる。なにをしているの？\n", 10), "ja"},
+		{"f.idx", "# VobSub index file, v7\nid: es, index: 0\n", "es"},
+		{"g.srt", srt("ok", "hmm"), ""},
+	} {
+		p := filepath.Join(dir, tc.Name)
+		if err := ioutil.WriteFile(p, []byte(tc.Content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := detectLanguage(p); got != tc.Expect {
+			t.Errorf("detectLanguage(%s) = %q, expected %q", tc.Name, got, tc.Expect)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+//Unsorted is written beside files which were set aside in
+//the unmatched or review directories, explaining why
+type Unsorted struct {
+	//Path is the original path of the file
+	Path  string `json:"path"`
+	Query string `json:"query"`
+	Year  string `json:"year,omitempty"`
+	Type  string `json:"type,omitempty"`
+	//Reason the file was not sorted
+	Reason    string `json:"reason"`
+	Threshold int    `json:"threshold"`
+	//Candidate is the best search result, when there was one
+	Candidate *mediasearch.Result `json:"candidate,omitempty"`
+	//Destination the file would have been sorted into
+	Destination string `json:"destination,omitempty"`
+}
+
+//inside returns whether path is dir or is inside dir
+func inside(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	path, dir = abs(path), abs(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+//unmatched returns whether err is a search which found
+//no match, other errors (eg. network) are not set aside
+func unmatched(err error) bool {
+	if _, ok := err.(*mediasearch.ThresholdError); ok {
+		return true
+	}
+	return err == mediasearch.ErrNoResults
+}
+
+//setAside sorts the file (and its companions) into dir
+//instead of the library, along with a json sidecar
+func (fs *fsSort) setAside(file *fileSort, dir string, u Unsorted) error {
+	u.Path = abs(file.path)
+	file.reason = u.Reason
+	newPath := uniquePath(filepath.Join(dir, filepath.Base(file.path)))
+	log.Printf("[#%d/%d] %s\n  └─> %s (%s)", file.id, len(fs.sorts), color.YellowString(file.path), color.YellowString(newPath), u.Reason)
+	if fs.DryRun {
+		return nil
+	}
+	companions, err := fs.findCompanions(file.path, false)
+	if err != nil {
+		return err
+	}
+	dirs, err := mkdirAll(dir)
+	if err != nil {
+		return err
+	}
+	used, err := fs.action(file.path, newPath)
+	if err != nil {
+		return err
+	}
+	fs.record(fs.newOperation(used, file.path, newPath, nil, dirs))
+	for i, p := range companionPaths(companions, newPath) {
+		if err := fs.actionCompanion(companions[i].path, p); err != nil {
+			log.Printf("[#%d/%d] %s\n  └─> %s", file.id, len(fs.sorts), color.RedString(companions[i].path), err)
+		}
+	}
+	b, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	sidecar := strings.TrimSuffix(newPath, filepath.Ext(newPath)) + ".json"
+	if err := ioutil.WriteFile(sidecar, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	fs.record(fs.newOperation(step{action: WriteAction}, "", sidecar, nil, nil))
+	return nil
+}
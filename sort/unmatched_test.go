@@ -0,0 +1,84 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mediasearch "github.com/jpillora/media-sort/search"
+)
+
+func TestSetAside(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Moive.2010.mkv", "in/Moive.2010.en.srt")
+	fs := testSort()
+	fs.Action = MoveAction
+	path := filepath.Join(dir, "in", "Moive.2010.mkv")
+	unmatched := filepath.Join(dir, "unmatched")
+	err := fs.setAside(&fileSort{id: 1, path: path}, unmatched, Unsorted{
+		Query:     "moive",
+		Reason:    "No results",
+		Candidate: &mediasearch.Result{Title: "Movie", Year: "2010", Accuracy: 80},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Moive.2010.mkv", "Moive.2010.en.srt"} {
+		if _, err := os.Stat(filepath.Join(unmatched, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(unmatched, "Moive.2010.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := Unsorted{}
+	if err := json.Unmarshal(b, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != path || u.Candidate == nil || u.Candidate.Accuracy != 80 {
+		t.Fatalf("unexpected sidecar: %s", b)
+	}
+	//set aside files are skipped by later scans
+	fs.UnmatchedDir = unmatched
+	if !inside(filepath.Join(unmatched, "Moive.2010.mkv"), fs.UnmatchedDir) || inside(path, fs.UnmatchedDir) {
+		t.Fatal("unexpected inside result")
+	}
+}
+
+func TestUnmatchedErrors(t *testing.T) {
+	failed := errors.New("Search failed (dial tcp: i/o timeout)")
+	defer func(s func(string, string, string, int) (mediasearch.Result, error)) { searchThreshold = s }(searchThreshold)
+	searchThreshold = func(query, year, mediatype string, threshold int) (mediasearch.Result, error) {
+		if query == "moive" {
+			return mediasearch.Result{}, mediasearch.ErrNoResults
+		}
+		return mediasearch.Result{}, failed
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	touch(t, dir, "in/Moive.2010.mkv", "in/Offline.2010.mkv")
+	fs := testSort()
+	fs.Action = MoveAction
+	fs.UnmatchedDir = filepath.Join(dir, "unmatched")
+	fs.sorts = map[string]*fileSort{}
+	//no match is set aside
+	if err := fs.sortFile(&fileSort{id: 1, path: filepath.Join(dir, "in", "Moive.2010.mkv")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(fs.UnmatchedDir, "Moive.2010.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	//a failed search is an error, and the file is left alone
+	path := filepath.Join(dir, "in", "Offline.2010.mkv")
+	if err := fs.sortFile(&fileSort{id: 2, path: path}); err != failed {
+		t.Fatalf("expected search error, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+}
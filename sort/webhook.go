@@ -0,0 +1,132 @@
+package mediasort
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+//webhookBackoff is the delay before the first
+//retry, which doubles with each attempt
+var webhookBackoff = time.Second
+
+//WebhookPayload is posted to the webhook as json, and is the data
+//of the webhook templates. Run events use --webhook-template and
+//file events use --webhook-file-template, either defaults to json.
+type WebhookPayload struct {
+	//Event is either "file" (with an Entry) or "run" (with a Report)
+	Event  string  `json:"event"`
+	Entry  *Entry  `json:"entry,omitempty"`
+	Report *Report `json:"report,omitempty"`
+}
+
+//webhook posts the outcome of runs and files
+type webhook struct {
+	client  *http.Client
+	url     string
+	retries int
+	//templates of each event
+	templates map[string]*template.Template
+}
+
+//webhookFuncs are available to webhook templates
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func (c *Config) webhook() (*webhook, error) {
+	if c.WebhookURL == "" {
+		return nil, nil
+	}
+	w := &webhook{
+		client:    &http.Client{Timeout: notifyTimeout},
+		url:       c.WebhookURL,
+		retries:   c.WebhookRetries,
+		templates: map[string]*template.Template{},
+	}
+	for event, text := range map[string]string{"run": c.WebhookTemplate, "file": c.WebhookFileTemplate} {
+		if text == "" {
+			continue
+		}
+		t, err := template.New(event).Funcs(webhookFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid webhook %s template: %s", event, err)
+		}
+		w.templates[event] = t
+	}
+	return w, nil
+}
+
+//body renders the payload, the template may produce
+//json or plain text for receivers like ntfy
+func (w *webhook) body(p WebhookPayload) ([]byte, string, error) {
+	t, ok := w.templates[p.Event]
+	if !ok {
+		b, err := json.Marshal(p)
+		return b, "application/json", err
+	}
+	buff := bytes.Buffer{}
+	if err := t.Execute(&buff, p); err != nil {
+		return nil, "", err
+	}
+	if json.Valid(buff.Bytes()) {
+		return buff.Bytes(), "application/json", nil
+	}
+	return buff.Bytes(), "text/plain; charset=utf-8", nil
+}
+
+//post sends the payload, retrying server errors
+//and rate limits with an exponential backoff
+func (w *webhook) post(p WebhookPayload) error {
+	body, contentType, err := w.body(p)
+	if err != nil {
+		return err
+	}
+	backoff := webhookBackoff
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = w.client.Post(w.url, contentType, bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 == 2 {
+				return nil
+			}
+			err = fmt.Errorf("Webhook responded %s", resp.Status)
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return err
+			}
+		}
+		if attempt >= w.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+//webhookFile posts the outcome of a file, when enabled
+func (fs *fsSort) webhookFile(file *fileSort) {
+	if fs.hook == nil || !fs.WebhookEachFile || fs.DryRun {
+		return
+	}
+	e := fs.entry(file)
+	if err := fs.hook.post(WebhookPayload{Event: "file", Entry: &e}); err != nil {
+		fs.hookFailed("webhook", file.path, err)
+	}
+}
+
+//webhookRun posts the report of the run
+func (fs *fsSort) webhookRun() {
+	if fs.hook == nil || fs.DryRun {
+		return
+	}
+	if err := fs.hook.post(WebhookPayload{Event: "run", Report: fs.report()}); err != nil {
+		fs.hookFailed("webhook", "", err)
+	}
+}
@@ -0,0 +1,90 @@
+package mediasort
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	defer func(b time.Duration) { webhookBackoff = b }(webhookBackoff)
+	webhookBackoff = 0
+	bodies := []string{}
+	types := []string{}
+	fail := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//fail the first request, which is retried
+		if fail > 0 {
+			fail--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		types = append(types, r.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+	fs := testSort()
+	fs.WebhookURL = server.URL
+	fs.WebhookEachFile = true
+	fs.WebhookRetries = 1
+	hook, err := fs.Config.webhook()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.hook = hook
+	fs.sorts = map[string]*fileSort{
+		"a.mkv": {path: "a.mkv", dest: "tv/A S01E01.mkv", result: &Result{Name: "A", MType: "series", Season: 1, Episode: 1}},
+		"b.mkv": {path: "b.mkv", err: errors.New("No results")},
+	}
+	fs.webhookFile(fs.sorts["a.mkv"])
+	fs.webhookRun()
+	if len(fs.hookErrors) > 0 || len(bodies) != 2 {
+		t.Fatalf("unexpected webhooks: %v %v", fs.hookErrors, bodies)
+	}
+	p := WebhookPayload{}
+	if err := json.Unmarshal([]byte(bodies[1]), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != "run" || len(p.Report.Sorted) != 1 || len(p.Report.Failed) != 1 || p.Report.Failed[0].Error != "No results" {
+		t.Fatalf("unexpected report: %s", bodies[1])
+	}
+	//templates may render text or json
+	for template, expect := range map[string]string{
+		`{{ len .Report.Sorted }} sorted`:                     "1 sorted|text/plain; charset=utf-8",
+		`{"text": {{ json (index .Report.Failed 0).Error }}}`: `{"text": "No results"}|application/json`,
+	} {
+		bodies, types = nil, nil
+		fs.WebhookTemplate = template
+		if fs.hook, err = fs.Config.webhook(); err != nil {
+			t.Fatal(err)
+		}
+		fs.webhookRun()
+		if len(bodies) != 1 || bodies[0]+"|"+types[0] != expect {
+			t.Fatalf("unexpected body: %v %v", bodies, types)
+		}
+	}
+	//file events have their own template
+	bodies, types = nil, nil
+	fs.WebhookTemplate = `{{ len .Report.Sorted }} sorted`
+	fs.WebhookFileTemplate = `sorted {{ .Entry.Destination }}`
+	if fs.hook, err = fs.Config.webhook(); err != nil {
+		t.Fatal(err)
+	}
+	fs.webhookFile(fs.sorts["a.mkv"])
+	fs.webhookRun()
+	if len(fs.hookErrors) > 0 || len(bodies) != 2 || bodies[0] != "sorted tv/A S01E01.mkv" || bodies[1] != "1 sorted" {
+		t.Fatalf("unexpected webhooks: %v %v", fs.hookErrors, bodies)
+	}
+	//failures are reported in the summary
+	fs.hookErrors = nil
+	fs.hook.url = server.URL + "/%zz"
+	fs.webhookRun()
+	if len(fs.hookErrors) != 1 {
+		t.Fatalf("expected webhook failure")
+	}
+}